@@ -0,0 +1,24 @@
+package database
+
+import (
+	"time"
+)
+
+// DBConfig database config, zero values keep the driver defaults
+type DBConfig struct {
+	URL string `yaml:"url" json:"url"`
+	// ReplicaURLs are the read replicas of the primary, replica-safe reads are spread over them
+	ReplicaURLs []string `yaml:"replicaURLs" json:"replicaURLs"`
+	// AutoMigrate applies pending schema migrations when the database is opened
+	AutoMigrate bool `yaml:"autoMigrate" json:"autoMigrate"`
+	// Timeout is the default timeout of every call whose context has no deadline, 0 means no timeout
+	Timeout         time.Duration `yaml:"timeout" json:"timeout" default:"30s"`
+	MaxOpenConns    int           `yaml:"maxOpenConns" json:"maxOpenConns" default:"20"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" json:"maxIdleConns" default:"10"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" json:"connMaxLifetime" default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime" json:"connMaxIdleTime" default:"5m"`
+	// StatementTimeout is enforced by postgres on every statement of the session
+	StatementTimeout time.Duration `yaml:"statementTimeout" json:"statementTimeout"`
+	// SlowThreshold is the elapsed time from which a query is logged as slow
+	SlowThreshold time.Duration `yaml:"slowThreshold" json:"slowThreshold" default:"200ms"`
+}
@@ -1,35 +1,225 @@
 package database
 
 import (
-	"log"
+	"context"
+	goerrors "errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
 	zlog "github.com/fiamma-chain/fiamma-go-sdk/log"
 )
 
 type Database struct {
 	DB  *gorm.DB
 	log *zlog.Logger
+	// replicas are the read replicas of DB
+	replicas []*gorm.DB
+	// next picks the replica of the next read, shared by all views
+	next *uint64
+	// operator is recorded in the audit events, see WithOperator
+	operator string
+	// noOutbox skips the outbox messages of the changes, see withoutOutbox
+	noOutbox bool
+	timeout  time.Duration
 }
 
+// NewDB connects to postgres
 func NewDB(url string) (*Database, error) {
-	newLogger := logger.New(
-		log.New(log.Writer(), "\r\n", log.LstdFlags), // io writer
-		logger.Config{
-			IgnoreRecordNotFoundError: true,
-		},
-	)
-	db, err := gorm.Open(postgres.Open(url), &gorm.Config{
-		Logger: newLogger,
+	return NewDBWithConfig(DBConfig{URL: url})
+}
+
+// NewDBWithConfig connects to postgres and its replicas, and applies pending migrations if cfg.AutoMigrate is set
+func NewDBWithConfig(cfg DBConfig) (*Database, error) {
+	db, err := openDB(cfg.URL, cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := &Database{
+		DB:      db,
+		log:     zlog.L().With(zlog.Any("service", "db")),
+		timeout: cfg.Timeout,
+		next:    new(uint64),
+	}
+	for _, dsn := range cfg.ReplicaURLs {
+		replica, err := openDB(dsn, cfg)
+		if err != nil {
+			d.Close()
+			return nil, err
+		}
+		d.replicas = append(d.replicas, replica)
+	}
+	if cfg.AutoMigrate {
+		if err := d.Migrate(); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func openDB(dsn string, cfg DBConfig) (*gorm.DB, error) {
+	if cfg.StatementTimeout > 0 {
+		dsn = withRuntimeParam(dsn, "statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: newGormLogger(globalGormLogger, cfg.SlowThreshold),
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &Database{
-		DB:  db,
-		log: zlog.L().With(zlog.Any("service", "db")),
-	}, nil
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+	return db, nil
+}
+
+// withRuntimeParam adds a postgres runtime parameter to either an url or a keyword/value dsn
+func withRuntimeParam(dsn, key, value string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		q.Set(key, value)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return fmt.Sprintf("%s %s=%s", dsn, key, value)
+}
+
+// Ping checks the connection to the primary
+func (db *Database) Ping(ctx context.Context) error {
+	return ping(ctx, db.DB)
+}
+
+// Health checks the connections to the primary and all replicas, it can back a readiness probe
+func (db *Database) Health(ctx context.Context) error {
+	if err := ping(ctx, db.DB); err != nil {
+		return errors.Errorf("primary is unhealthy: %s", err.Error())
+	}
+	for i, replica := range db.replicas {
+		if err := ping(ctx, replica); err != nil {
+			return errors.Errorf("replica %d is unhealthy: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// Close closes the connection pools of the primary and replicas
+func (db *Database) Close() error {
+	var res error
+	for _, g := range append([]*gorm.DB{db.DB}, db.replicas...) {
+		sqlDB, err := g.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+func ping(ctx context.Context, g *gorm.DB) error {
+	sqlDB, err := g.DB()
+	if err != nil {
+		return err
+	}
+	return ctxError(ctx, sqlDB.PingContext(ctx))
+}
+
+// Primary returns a view of the database reading from the primary, for flows reading their own writes
+func (db *Database) Primary() *Database {
+	n := *db
+	n.replicas = nil
+	return &n
+}
+
+// reader returns the connection serving replica-safe reads, the replicas are picked in turn
+func (db *Database) reader() *gorm.DB {
+	if len(db.replicas) == 0 {
+		return db.DB
+	}
+	i := atomic.AddUint64(db.next, 1)
+	return db.replicas[i%uint64(len(db.replicas))]
+}
+
+// ctx returns the context of the session, it is used by the methods without context,
+// so that they keep the context of the transaction inside WithTx
+func (db *Database) ctx() context.Context {
+	if db.DB.Statement.Context != nil {
+		return db.DB.Statement.Context
+	}
+	return context.Background()
+}
+
+// withTimeout applies the default timeout if ctx has no deadline
+func (db *Database) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || db.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, db.timeout)
+}
+
+// pgUniqueViolation is the SQLSTATE of unique constraint violations
+const pgUniqueViolation = "23505"
+
+// duplicatedKeyError reports unique violations as gorm.ErrDuplicatedKey, like MemoryStore does
+func duplicatedKeyError(err error) error {
+	var pgErr *pgconn.PgError
+	if goerrors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return gorm.ErrDuplicatedKey
+	}
+	return err
+}
+
+// pgQueryCanceled is the SQLSTATE of statements canceled on request of the client or by statement_timeout
+const pgQueryCanceled = "57014"
+
+// ctxError converts errors caused by the cancellation or deadline of ctx into coded errors,
+// other errors are returned as is even if ctx is done meanwhile
+func ctxError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(errors.Coder); ok {
+		return err
+	}
+	var pgErr *pgconn.PgError
+	switch {
+	case goerrors.Is(err, context.Canceled):
+		return ErrCanceled
+	case goerrors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case goerrors.As(err, &pgErr) && pgErr.Code == pgQueryCanceled:
+		if ctx.Err() == context.Canceled {
+			return ErrCanceled
+		}
+		return ErrTimeout
+	default:
+		return err
+	}
 }
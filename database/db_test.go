@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// testDBURLEnv is the environment variable holding the url of the postgres database used by the tests,
+// the tests which need one are skipped if it is not set
+const testDBURLEnv = "FIAMMA_TEST_DB_URL"
+
+// testDB opens the test database, or skips the test if there is none
+func testDB(t *testing.T) *Database {
+	t.Helper()
+	url := os.Getenv(testDBURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDBURLEnv)
+	}
+	db, err := NewDBWithConfig(DBConfig{URL: url, AutoMigrate: true})
+	if err != nil {
+		t.Fatalf("failed to open the test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() }) //nolint:errcheck
+	return db
+}
+
+func TestDatabaseReader(t *testing.T) {
+	primary, r1, r2 := &gorm.DB{}, &gorm.DB{}, &gorm.DB{}
+
+	db := &Database{DB: primary, next: new(uint64)}
+	assert.Same(t, primary, db.reader())
+	assert.Same(t, primary, db.reader())
+
+	db = &Database{DB: primary, replicas: []*gorm.DB{r1, r2}, next: new(uint64)}
+	first := db.reader()
+	assert.True(t, first == r1 || first == r2)
+	second := db.reader()
+	assert.NotSame(t, first, second)
+	assert.Same(t, first, db.reader())
+	assert.Same(t, second, db.reader())
+
+	p := db.Primary()
+	assert.Same(t, primary, p.reader())
+	assert.Same(t, primary, p.DB)
+	// the view does not change the database
+	assert.Len(t, db.replicas, 2)
+}
+
+func TestCtxError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	queryCanceled := &pgconn.PgError{Code: pgQueryCanceled}
+	uniqueViolation := &pgconn.PgError{Code: pgUniqueViolation}
+
+	assert.NoError(t, ctxError(canceled, nil))
+	assert.Equal(t, ErrCanceled, ctxError(canceled, context.Canceled))
+	assert.Equal(t, ErrTimeout, ctxError(expired, fmt.Errorf("query: %w", context.DeadlineExceeded)))
+	assert.Equal(t, ErrCanceled, ctxError(canceled, queryCanceled))
+	assert.Equal(t, ErrTimeout, ctxError(expired, queryCanceled))
+	// statement_timeout cancels the query while ctx is still alive
+	assert.Equal(t, ErrTimeout, ctxError(context.Background(), queryCanceled))
+
+	// errors which are not caused by ctx are kept even if it is done meanwhile
+	assert.Equal(t, uniqueViolation, ctxError(expired, uniqueViolation))
+	assert.Equal(t, gorm.ErrRecordNotFound, ctxError(canceled, gorm.ErrRecordNotFound))
+	assert.Equal(t, ErrLpTxStateConflict, ctxError(expired, ErrLpTxStateConflict))
+}
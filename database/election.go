@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// LockLeaderBackend is an election backend holding the leadership with a session advisory lock,
+// which is lost as soon as the connection of the leader is broken
+type LockLeaderBackend struct {
+	mutex *Mutex
+}
+
+// NewLockLeaderBackend creates an advisory lock election backend
+func (db *Database) NewLockLeaderBackend(name string) *LockLeaderBackend {
+	return &LockLeaderBackend{mutex: db.NewMutex("leader." + name)}
+}
+
+func (b *LockLeaderBackend) Acquire(ctx context.Context) (bool, error) {
+	return b.mutex.TryLock(ctx)
+}
+
+func (b *LockLeaderBackend) Renew(ctx context.Context) (bool, error) {
+	return b.mutex.Check(ctx)
+}
+
+func (b *LockLeaderBackend) Release(_ context.Context) error {
+	if err := b.mutex.Unlock(); err != nil && err != ErrLockNotHeld {
+		return err
+	}
+	return nil
+}
+
+// LeaseLeaderBackend is an election backend holding the leadership with a lease stored in the leader_leases table.
+// The lease expires after ttl unless renewed, so the renew interval must be well below ttl
+// and the lease duration of the elector must not exceed it, and the clocks of the instances must be roughly in sync.
+type LeaseLeaderBackend struct {
+	db     *Database
+	name   string
+	holder string
+	ttl    time.Duration
+}
+
+// NewLeaseLeaderBackend creates a lease election backend, holder must be unique per instance, like the hostname
+func (db *Database) NewLeaseLeaderBackend(name, holder string, ttl time.Duration) *LeaseLeaderBackend {
+	return &LeaseLeaderBackend{
+		db:     db,
+		name:   name,
+		holder: holder,
+		ttl:    ttl,
+	}
+}
+
+func (b *LeaseLeaderBackend) Acquire(ctx context.Context) (bool, error) {
+	return b.claim(ctx)
+}
+
+func (b *LeaseLeaderBackend) Renew(ctx context.Context) (bool, error) {
+	return b.claim(ctx)
+}
+
+// Release expires the lease if it is still held
+func (b *LeaseLeaderBackend) Release(ctx context.Context) error {
+	ctx, cancel := b.db.withTimeout(ctx)
+	defer cancel()
+	err := b.db.DB.WithContext(ctx).
+		Exec(`UPDATE leader_leases SET expire_at = ? WHERE name = ? AND holder = ?`, time.Time{}, b.name, b.holder).Error
+	return ctxError(ctx, err)
+}
+
+// claim extends the lease if it is free, expired or already held by the holder
+func (b *LeaseLeaderBackend) claim(ctx context.Context) (bool, error) {
+	ctx, cancel := b.db.withTimeout(ctx)
+	defer cancel()
+	now := time.Now()
+	res := b.db.DB.WithContext(ctx).Exec(`INSERT INTO leader_leases (name, holder, expire_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expire_at = EXCLUDED.expire_at
+		WHERE leader_leases.holder = EXCLUDED.holder OR leader_leases.expire_at <= ?`,
+		b.name, b.holder, now.Add(b.ttl), now)
+	if res.Error != nil {
+		return false, ctxError(ctx, res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
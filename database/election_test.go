@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaseLeaderBackend(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	name := "test." + strconv.FormatInt(time.Now().UnixNano(), 36)
+	a := db.NewLeaseLeaderBackend(name, "a", time.Minute)
+	b := db.NewLeaseLeaderBackend(name, "b", time.Minute)
+
+	ok, err := a.Acquire(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = b.Acquire(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = a.Renew(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// releasing a lease held by another holder is a no-op
+	assert.NoError(t, b.Release(ctx))
+	ok, err = b.Acquire(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, a.Release(ctx))
+	ok, err = b.Acquire(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = a.Renew(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// the lease does not touch the properties
+	_, err = db.GetProperty("leader." + name)
+	assert.Error(t, err)
+}
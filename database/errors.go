@@ -0,0 +1,39 @@
+package database
+
+import (
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/ginctx"
+)
+
+var (
+	// ErrLpTxIllegalTransition indicates the lp transaction state machine has no such edge
+	ErrLpTxIllegalTransition = errors.CodeError(ginctx.ErrRequestParamInvalid, "illegal lp transaction state transition")
+
+	// ErrLpTxStateConflict indicates the lp transaction is no longer in the expected state,
+	// usually because another worker has already moved it
+	ErrLpTxStateConflict = errors.CodeError(ginctx.ErrResourceConflict, "lp transaction state has been changed")
+
+	// ErrLpTxVersionConflict indicates the lp transaction has been updated since the caller read it
+	ErrLpTxVersionConflict = errors.CodeError(ginctx.ErrResourceConflict, "lp transaction has been modified by others")
+
+	// ErrLpTxPayloadConflict indicates the tx hash is already known with a different payload
+	ErrLpTxPayloadConflict = errors.CodeError(ginctx.ErrResourceConflict, "lp transaction already exists with a different payload")
+
+	// ErrCanceled indicates the call is aborted since its context is canceled
+	ErrCanceled = errors.CodeError(ginctx.ErrRequestCanceled, "database call is canceled")
+
+	// ErrTimeout indicates the call is aborted since its context deadline is exceeded
+	ErrTimeout = errors.CodeError(ginctx.ErrRequestTimeout, "database call is timed out")
+
+	// ErrInvalidInterval indicates the bucket interval is not one of hour, day, week and month
+	ErrInvalidInterval = errors.CodeError(ginctx.ErrRequestParamInvalid, "invalid interval")
+
+	// ErrInvalidCursor indicates the page cursor is malformed
+	ErrInvalidCursor = errors.CodeError(ginctx.ErrRequestParamInvalid, "invalid page cursor")
+
+	// ErrLockHeld indicates the mutex is already held by the caller
+	ErrLockHeld = errors.CodeError(ginctx.ErrResourceConflict, "lock is already held")
+
+	// ErrLockNotHeld indicates the mutex is unlocked without being held
+	ErrLockNotHeld = errors.CodeError(ginctx.ErrResourceConflict, "lock is not held")
+)
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeResult is the scripted answer to a statement, a query returns the rows of columns
+// and an exec reports affected rows
+type fakeResult struct {
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+type fakeStmt struct {
+	query string
+	args  []interface{}
+}
+
+// fakeDB is a scripted database/sql driver, it records the statements sent by gorm and answers them
+// with handle, so that the SQL flows can be tested without postgres
+type fakeDB struct {
+	mu     sync.Mutex
+	stmts  []fakeStmt
+	handle func(query string, args []interface{}) fakeResult
+}
+
+// newFakeDatabase returns a database backed by the scripted driver, statements not handled get an empty result
+func newFakeDatabase(t *testing.T, handle func(query string, args []interface{}) fakeResult) (*Database, *fakeDB) {
+	f := &fakeDB{handle: handle}
+	g, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(f)}), &gorm.Config{
+		Logger:               logger.Discard,
+		DisableAutomaticPing: true,
+	})
+	assert.NoError(t, err)
+	return &Database{DB: g, next: new(uint64)}, f
+}
+
+// queries returns the statements received so far
+func (f *fakeDB) queries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var res []string
+	for _, s := range f.stmts {
+		res = append(res, s.query)
+	}
+	return res
+}
+
+// assertStatements checks that the statements received so far start with prefixes, in order
+func (f *fakeDB) assertStatements(t *testing.T, prefixes ...string) {
+	queries := f.queries()
+	if !assert.Len(t, queries, len(prefixes), queries) {
+		return
+	}
+	for i, prefix := range prefixes {
+		assert.True(t, strings.HasPrefix(queries[i], prefix), "statement %d is %q, expected %q", i, queries[i], prefix)
+	}
+}
+
+// find returns the first statement starting with prefix
+func (f *fakeDB) find(prefix string) (fakeStmt, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.stmts {
+		if strings.HasPrefix(s.query, prefix) {
+			return s, true
+		}
+	}
+	return fakeStmt{}, false
+}
+
+func (f *fakeDB) run(query string, named []driver.NamedValue) fakeResult {
+	args := make([]interface{}, len(named))
+	for i, a := range named {
+		args[i] = a.Value
+	}
+	f.mu.Lock()
+	f.stmts = append(f.stmts, fakeStmt{query: query, args: args})
+	f.mu.Unlock()
+	if f.handle == nil {
+		return fakeResult{}
+	}
+	return f.handle(query, args)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, driver.ErrSkip
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.run("BEGIN", nil)
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.run("COMMIT", nil)
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.run("ROLLBACK", nil)
+	return nil
+}
+
+// CheckNamedValue passes all arguments as is, they are only recorded
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res := c.db.run(query, args)
+	if res.err != nil {
+		return nil, res.err
+	}
+	return driver.RowsAffected(res.affected), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res := c.db.run(query, args)
+	if res.err != nil {
+		return nil, res.err
+	}
+	return &fakeRows{columns: res.columns, rows: res.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
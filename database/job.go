@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	zlog "github.com/fiamma-chain/fiamma-go-sdk/log"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+	"github.com/fiamma-chain/fiamma-go-sdk/utils"
+)
+
+// DefaultJobMaxAttempts is the number of attempts of a job enqueued without MaxAttempts
+const DefaultJobMaxAttempts = 10
+
+// EnqueueJob adds a pending job to its queue, it runs at RunAt or as soon as possible if RunAt is zero
+func (db *Database) EnqueueJob(job *spec.Job) error {
+	return db.EnqueueJobCtx(db.ctx(), job)
+}
+
+func (db *Database) EnqueueJobCtx(ctx context.Context, job *spec.Job) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	job.ID = 0
+	job.State = spec.JobStatePending
+	job.Attempts = 0
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultJobMaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	return ctxError(ctx, db.DB.WithContext(ctx).Create(job).Error)
+}
+
+func (db *Database) GetJob(id uint) (*spec.Job, error) {
+	return db.GetJobCtx(db.ctx(), id)
+}
+
+func (db *Database) GetJobCtx(ctx context.Context, id uint) (*spec.Job, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var job spec.Job
+	if err := db.DB.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, ctxError(ctx, err)
+	}
+	return &job, nil
+}
+
+// RetryDeadJobs makes the dead jobs of the queue pending again with fresh attempts, and returns their number
+func (db *Database) RetryDeadJobs(queue string) (int64, error) {
+	return db.RetryDeadJobsCtx(db.ctx(), queue)
+}
+
+func (db *Database) RetryDeadJobsCtx(ctx context.Context, queue string) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	res := db.DB.WithContext(ctx).Model(&spec.Job{}).
+		Where("queue = ? AND state = ?", queue, spec.JobStateDead).
+		Updates(map[string]interface{}{
+			"state":       spec.JobStatePending,
+			"attempts":    0,
+			"run_at":      time.Now(),
+			"finished_at": nil,
+		})
+	return res.RowsAffected, ctxError(ctx, res.Error)
+}
+
+// JobHandler runs a job, the job is retried if an error is returned
+type JobHandler func(ctx context.Context, job *spec.Job) error
+
+// JobWorkerConfig job worker config
+type JobWorkerConfig struct {
+	// Concurrency is the number of jobs of the queue run at the same time by the worker
+	Concurrency int `yaml:"concurrency" json:"concurrency" default:"4"`
+	// PollInterval is how often the queue is polled when it is drained
+	PollInterval time.Duration `yaml:"pollInterval" json:"pollInterval" default:"1s"`
+	// LockTimeout bounds the run of a job, after which it is considered abandoned and runs again
+	LockTimeout time.Duration `yaml:"lockTimeout" json:"lockTimeout" default:"5m"`
+	// MinBackoff and MaxBackoff bound the exponential delay between attempts, a random jitter is applied
+	MinBackoff time.Duration `yaml:"minBackoff" json:"minBackoff" default:"1s"`
+	MaxBackoff time.Duration `yaml:"maxBackoff" json:"maxBackoff" default:"1h"`
+}
+
+// JobWorker runs the jobs of a queue. Workers of the same queue may run in several processes,
+// each job is claimed by a single worker, and jobs abandoned by a crashed worker run again after LockTimeout.
+type JobWorker struct {
+	db      *Database
+	queue   string
+	handler JobHandler
+	cfg     JobWorkerConfig
+	log     *zlog.Logger
+
+	mu sync.Mutex
+	// deadLetterAt is when the abandoned jobs are looked for next
+	deadLetterAt time.Time
+}
+
+// NewJobWorker creates a worker of the queue, zero config values are set to their defaults
+func NewJobWorker(db *Database, queue string, handler JobHandler, cfg JobWorkerConfig) (*JobWorker, error) {
+	if err := utils.SetDefaults(&cfg); err != nil {
+		return nil, err
+	}
+	return &JobWorker{
+		db:      db,
+		queue:   queue,
+		handler: handler,
+		cfg:     cfg,
+		log:     zlog.L().With(zlog.Any("service", "job"), zlog.Any("queue", queue)),
+	}, nil
+}
+
+// Run runs jobs until ctx is done, and waits for the running jobs to return
+func (w *JobWorker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				ok, err := w.RunOnce(ctx)
+				if err != nil && ctx.Err() == nil {
+					w.log.Error("failed to run job", zlog.Error(err))
+				}
+				if ok && err == nil {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(w.cfg.PollInterval):
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// RunOnce claims and runs a single due job, and reports whether there was one
+func (w *JobWorker) RunOnce(ctx context.Context) (bool, error) {
+	job, err := w.claim(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+	jobCtx, cancel := context.WithTimeout(ctx, w.cfg.LockTimeout)
+	err = w.handle(jobCtx, job)
+	cancel()
+	// record the result even if the worker is stopping, so the job does not wait for its lock to expire
+	return true, w.complete(context.Background(), job, err)
+}
+
+// claim locks the next due job, either pending or abandoned, and marks it as running.
+// Abandoned jobs which exhausted their attempts are dead-lettered instead of running again.
+func (w *JobWorker) claim(ctx context.Context) (*spec.Job, error) {
+	ctx, cancel := w.db.withTimeout(ctx)
+	defer cancel()
+	now := time.Now()
+	if w.deadLetterDue(now) {
+		err := w.db.DB.WithContext(ctx).Model(&spec.Job{}).
+			Where("queue = ? AND state = ? AND locked_until < ? AND attempts >= max_attempts", w.queue, spec.JobStateRunning, now).
+			Updates(map[string]interface{}{
+				"state":        spec.JobStateDead,
+				"locked_until": nil,
+				"last_error":   "lease expired",
+				"finished_at":  now,
+			}).Error
+		if err != nil {
+			return nil, ctxError(ctx, err)
+		}
+	}
+	var jobs []spec.Job
+	err := w.db.DB.WithContext(ctx).Raw(`UPDATE jobs SET state = ?, attempts = attempts + 1, locked_until = ?, updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE queue = ? AND ((state = ? AND run_at <= ?) OR (state = ? AND locked_until < ? AND attempts < max_attempts))
+			ORDER BY run_at, id LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		) RETURNING *`,
+		spec.JobStateRunning, now.Add(w.cfg.LockTimeout), now,
+		w.queue, spec.JobStatePending, now, spec.JobStateRunning, now,
+	).Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, ctxError(ctx, err)
+	}
+	return &jobs[0], nil
+}
+
+// deadLetterDue reports whether the abandoned jobs should be dead-lettered, at most once per LockTimeout
+// since a job is abandoned no sooner than LockTimeout after it has been claimed
+func (w *JobWorker) deadLetterDue(now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if now.Before(w.deadLetterAt) {
+		return false
+	}
+	w.deadLetterAt = now.Add(w.cfg.LockTimeout)
+	return true
+}
+
+func (w *JobWorker) handle(ctx context.Context, job *spec.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("job panicked: %v", r)
+		}
+	}()
+	return w.handler(ctx, job)
+}
+
+// complete records the result, unless the job has been claimed again after its lock expired
+func (w *JobWorker) complete(ctx context.Context, job *spec.Job, jobErr error) error {
+	ctx, cancel := w.db.withTimeout(ctx)
+	defer cancel()
+	if jobErr != nil {
+		w.log.Warn("job failed", zlog.Any("id", job.ID), zlog.Any("attempts", job.Attempts), zlog.Error(jobErr))
+	}
+	values := w.result(job, jobErr, time.Now())
+	res := w.db.DB.WithContext(ctx).Model(&spec.Job{}).
+		Where("id = ? AND state = ? AND attempts = ?", job.ID, spec.JobStateRunning, job.Attempts).
+		Updates(values)
+	if res.Error != nil {
+		return ctxError(ctx, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errors.Errorf("job %d has been claimed by another worker", job.ID)
+	}
+	return nil
+}
+
+// result returns the columns recording the result of a run, failed jobs are retried
+// with a backoff until they exhausted their attempts
+func (w *JobWorker) result(job *spec.Job, jobErr error, now time.Time) map[string]interface{} {
+	if jobErr == nil {
+		return map[string]interface{}{
+			"state":        spec.JobStateDone,
+			"locked_until": nil,
+			"last_error":   "",
+			"finished_at":  now,
+		}
+	}
+	if job.Attempts >= job.MaxAttempts {
+		return map[string]interface{}{
+			"state":        spec.JobStateDead,
+			"locked_until": nil,
+			"last_error":   jobErr.Error(),
+			"finished_at":  now,
+		}
+	}
+	return map[string]interface{}{
+		"state":        spec.JobStatePending,
+		"locked_until": nil,
+		"last_error":   jobErr.Error(),
+		"run_at":       now.Add(jobBackoff(w.cfg.MinBackoff, w.cfg.MaxBackoff, job.Attempts)),
+		"finished_at":  nil,
+	}
+}
+
+// jobBackoff is the exponential delay before the next attempt, with an equal jitter so that
+// jobs failing together are not retried together
+func jobBackoff(min, max time.Duration, attempts int) time.Duration {
+	d := expBackoff(min, max, attempts)
+	if half := int64(d / 2); half > 0 {
+		d = time.Duration(half + rand.Int63n(half+1))
+	}
+	return d
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+func TestJobWorkerHandlePanic(t *testing.T) {
+	w, err := NewJobWorker(nil, "test", func(context.Context, *spec.Job) error {
+		panic("boom")
+	}, JobWorkerConfig{})
+	assert.NoError(t, err)
+	err = w.handle(context.Background(), &spec.Job{ID: 1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "job panicked: boom")
+
+	w.handler = func(context.Context, *spec.Job) error { return nil }
+	assert.NoError(t, w.handle(context.Background(), &spec.Job{ID: 1}))
+}
+
+func TestJobWorkerResult(t *testing.T) {
+	w, err := NewJobWorker(nil, "test", nil, JobWorkerConfig{})
+	assert.NoError(t, err)
+	now := time.Now()
+
+	values := w.result(&spec.Job{Attempts: 1, MaxAttempts: 3}, nil, now)
+	assert.Equal(t, spec.JobStateDone, values["state"])
+	assert.Equal(t, now, values["finished_at"])
+	assert.Equal(t, "", values["last_error"])
+
+	values = w.result(&spec.Job{Attempts: 1, MaxAttempts: 3}, errors.New("failed"), now)
+	assert.Equal(t, spec.JobStatePending, values["state"])
+	runAt := values["run_at"].(time.Time)
+	assert.False(t, runAt.Before(now.Add(time.Second/2)))
+	assert.False(t, runAt.After(now.Add(time.Second)))
+	assert.Equal(t, "failed", values["last_error"])
+	assert.Nil(t, values["finished_at"])
+
+	values = w.result(&spec.Job{Attempts: 2, MaxAttempts: 3}, errors.New("failed"), now)
+	assert.Equal(t, spec.JobStatePending, values["state"])
+	runAt = values["run_at"].(time.Time)
+	assert.False(t, runAt.Before(now.Add(time.Second)))
+	assert.False(t, runAt.After(now.Add(2*time.Second)))
+
+	values = w.result(&spec.Job{Attempts: 3, MaxAttempts: 3}, errors.New("failed"), now)
+	assert.Equal(t, spec.JobStateDead, values["state"])
+	assert.Equal(t, now, values["finished_at"])
+	assert.NotContains(t, values, "run_at")
+}
+
+func TestJobWorkerDeadLetterDue(t *testing.T) {
+	w, err := NewJobWorker(nil, "test", nil, JobWorkerConfig{LockTimeout: time.Minute})
+	assert.NoError(t, err)
+	now := time.Now()
+
+	assert.True(t, w.deadLetterDue(now))
+	assert.False(t, w.deadLetterDue(now))
+	assert.False(t, w.deadLetterDue(now.Add(59*time.Second)))
+	assert.True(t, w.deadLetterDue(now.Add(time.Minute)))
+	assert.False(t, w.deadLetterDue(now.Add(time.Minute+time.Second)))
+}
@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"gorm.io/gorm"
+
+	zlog "github.com/fiamma-chain/fiamma-go-sdk/log"
+)
+
+// Mutex is a distributed mutex on top of a postgres session-level advisory lock.
+// The lock pins a connection while it is held, and is released when the context passed to
+// Lock or TryLock is done, or when the connection is lost.
+// Locks with the same name are mutually exclusive across all processes using the database.
+type Mutex struct {
+	db   *Database
+	name string
+	key  int64
+
+	// lock pins a connection holding the advisory lock, it is lockConn unless replaced by tests
+	lock func(ctx context.Context, try bool) (*sql.Conn, bool, error)
+
+	mu sync.Mutex
+	// acquiring is set while a Lock or TryLock waits for the lock without holding mu
+	acquiring bool
+	conn      *sql.Conn
+	// done is closed once the current hold is released
+	done chan struct{}
+}
+
+// NewMutex creates a mutex, the name is hashed into the advisory lock key space
+func (db *Database) NewMutex(name string) *Mutex {
+	m := &Mutex{
+		db:   db,
+		name: name,
+		key:  advisoryLockKey(name),
+	}
+	m.lock = m.lockConn
+	return m
+}
+
+// Lock blocks until the lock is acquired or ctx is done, the default timeout does not apply.
+// ErrLockHeld is returned if the mutex is already held or being acquired.
+func (m *Mutex) Lock(ctx context.Context) error {
+	_, err := m.acquire(ctx, false)
+	return err
+}
+
+// TryLock acquires the lock without waiting, and reports whether it is acquired
+func (m *Mutex) TryLock(ctx context.Context) (bool, error) {
+	return m.acquire(ctx, true)
+}
+
+// Unlock releases the lock, ErrLockNotHeld is returned if it is not held
+func (m *Mutex) Unlock() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conn == nil {
+		return ErrLockNotHeld
+	}
+	m.release()
+	return nil
+}
+
+func (m *Mutex) acquire(ctx context.Context, try bool) (bool, error) {
+	m.mu.Lock()
+	if m.conn != nil || m.acquiring {
+		m.mu.Unlock()
+		return false, ErrLockHeld
+	}
+	m.acquiring = true
+	m.mu.Unlock()
+
+	// waiting for the lock may last long, the other methods must not block meanwhile
+	conn, acquired, err := m.lock(ctx, try)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.acquiring = false
+	if err != nil || !acquired {
+		return false, err
+	}
+	m.conn = conn
+	done := make(chan struct{})
+	m.done = done
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			// the mutex may have been unlocked and locked again meanwhile
+			if m.done == done {
+				m.release()
+			}
+		case <-done:
+		}
+	}()
+	return true, nil
+}
+
+// lockConn takes the advisory lock on a dedicated connection, which is returned if the lock is acquired
+func (m *Mutex) lockConn(ctx context.Context, try bool) (*sql.Conn, bool, error) {
+	sqlDB, err := m.db.DB.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, ctxError(ctx, err)
+	}
+	acquired := true
+	if try {
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", m.key).Scan(&acquired)
+	} else {
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", m.key)
+	}
+	if err != nil {
+		// the session state is unknown, never give it back to the pool
+		discardConn(conn)
+		return nil, false, ctxError(ctx, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+	return conn, true, nil
+}
+
+// Check reports whether the lock is still held, it is lost if the pinned connection is broken
+func (m *Mutex) Check(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conn == nil {
+		return false, nil
+	}
+	if err := m.conn.PingContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			return true, ctxError(ctx, err)
+		}
+		close(m.done)
+		discardConn(m.conn)
+		m.conn, m.done = nil, nil
+		return false, err
+	}
+	return true, nil
+}
+
+// release must be called with m.mu held
+func (m *Mutex) release() {
+	close(m.done)
+	conn := m.conn
+	m.conn, m.done = nil, nil
+
+	ctx, cancel := m.db.withTimeout(context.Background())
+	defer cancel()
+	var released bool
+	err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", m.key).Scan(&released)
+	if err != nil || !released {
+		// closing the session releases all of its advisory locks
+		m.db.log.Warn("failed to release advisory lock, discarding the connection",
+			zlog.Any("name", m.name), zlog.Error(err))
+		discardConn(conn)
+		return
+	}
+	conn.Close()
+}
+
+// discardConn closes the connection and removes it from the pool
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error { //nolint:errcheck
+		return driver.ErrBadConn
+	})
+	conn.Close()
+}
+
+// WithLock runs fn while holding the session lock, fn must return once ctx is done
+func (db *Database) WithLock(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m := db.NewMutex(name)
+	if err := m.Lock(ctx); err != nil {
+		return err
+	}
+	defer m.Unlock() //nolint:errcheck
+	return fn(ctx)
+}
+
+// TryWithLock runs fn if the session lock can be acquired without waiting, and reports whether fn ran
+func (db *Database) TryWithLock(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m := db.NewMutex(name)
+	ok, err := m.TryLock(ctx)
+	if err != nil || !ok {
+		return false, err
+	}
+	defer m.Unlock() //nolint:errcheck
+	return true, fn(ctx)
+}
+
+// WithXactLock runs fn in a transaction holding a transaction-level advisory lock,
+// which is released on commit or rollback. Waiting for the lock is bounded by the default timeout.
+func (db *Database) WithXactLock(ctx context.Context, name string, fn func(tx Store) error) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", advisoryLockKey(name)).Error; err != nil {
+			return err
+		}
+		return fn(db.withDB(tx))
+	})
+	return ctxError(ctx, err)
+}
+
+// TryWithXactLock runs fn in a transaction if the transaction-level lock can be acquired without waiting,
+// and reports whether fn ran
+func (db *Database) TryWithXactLock(ctx context.Context, name string, fn func(tx Store) error) (bool, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var acquired bool
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", advisoryLockKey(name)).Scan(&acquired).Error; err != nil {
+			return err
+		}
+		if !acquired {
+			return nil
+		}
+		return fn(db.withDB(tx))
+	})
+	return acquired, ctxError(ctx, err)
+}
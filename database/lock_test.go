@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+)
+
+func TestMutex(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	m1, m2 := db.NewMutex(t.Name()), db.NewMutex(t.Name())
+
+	assert.NoError(t, m1.Lock(ctx))
+	assert.Equal(t, ErrLockHeld, m1.Lock(ctx))
+	ok, err := m2.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = m1.Check(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, m1.Unlock())
+	assert.Equal(t, ErrLockNotHeld, m1.Unlock())
+	ok, err = m2.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, m2.Unlock())
+}
+
+func TestMutexReleasedOnContextDone(t *testing.T) {
+	db := testDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	m1, m2 := db.NewMutex(t.Name()), db.NewMutex(t.Name())
+
+	assert.NoError(t, m1.Lock(ctx))
+	cancel()
+	lockCtx, lockCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer lockCancel()
+	assert.NoError(t, m2.Lock(lockCtx))
+	assert.Equal(t, ErrLockNotHeld, m1.Unlock())
+	assert.NoError(t, m2.Unlock())
+}
+
+func TestWithLockReleasedOnPanic(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	assert.Panics(t, func() {
+		db.WithLock(ctx, t.Name(), func(context.Context) error { //nolint:errcheck
+			panic("boom")
+		})
+	})
+	ran, err := db.TryWithLock(ctx, t.Name(), func(ctx context.Context) error {
+		// the lock is held by this session only
+		ok, err := db.NewMutex(t.Name()).TryLock(ctx)
+		assert.False(t, ok)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.True(t, ran)
+}
+
+func TestWithXactLock(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	failed := errors.New("failed")
+
+	err := db.WithXactLock(ctx, t.Name(), func(Store) error {
+		ran, err := db.TryWithXactLock(ctx, t.Name(), func(Store) error { return nil })
+		assert.NoError(t, err)
+		assert.False(t, ran)
+		return failed
+	})
+	assert.Equal(t, failed, errors.Cause(err))
+
+	// the lock is released with the rolled back transaction
+	ran, err := db.TryWithXactLock(ctx, t.Name(), func(Store) error { return nil })
+	assert.NoError(t, err)
+	assert.True(t, ran)
+}
+
+func TestMutexWaitDoesNotBlock(t *testing.T) {
+	m := (&Database{}).NewMutex(t.Name())
+	waiting := make(chan struct{})
+	m.lock = func(ctx context.Context, _ bool) (*sql.Conn, bool, error) {
+		close(waiting)
+		<-ctx.Done()
+		return nil, false, ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	locked := make(chan error, 1)
+	go func() {
+		locked <- m.Lock(ctx)
+	}()
+	<-waiting
+
+	// the other methods return while Lock is waiting
+	assert.Equal(t, ErrLockNotHeld, m.Unlock())
+	ok, err := m.Check(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, ErrLockHeld, m.Lock(context.Background()))
+	ok, err = m.TryLock(context.Background())
+	assert.Equal(t, ErrLockHeld, err)
+	assert.False(t, ok)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-locked)
+	// the mutex can be locked again once the wait is over
+	m.lock = func(context.Context, bool) (*sql.Conn, bool, error) {
+		return nil, false, nil
+	}
+	ok, err = m.TryLock(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+
+	zlog "github.com/fiamma-chain/fiamma-go-sdk/log"
+)
+
+// gormLogger routes gorm logs into the sdk logger, queries are logged at debug level,
+// slow queries at warn level and failed queries at error level
+type gormLogger struct {
+	// log returns the logger at the time of the call, so that a logger replaced later by log.Init is used
+	log           func() *zlog.Logger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+// globalGormLogger returns the global logger of the sdk tagged as gorm
+func globalGormLogger() *zlog.Logger {
+	return zlog.L().With(zlog.Any("service", "gorm"))
+}
+
+func newGormLogger(log func() *zlog.Logger, slowThreshold time.Duration) logger.Interface {
+	return &gormLogger{
+		log:           log,
+		level:         logger.Info,
+		slowThreshold: slowThreshold,
+	}
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	n := *l
+	n.level = level
+	return &n
+}
+
+func (l *gormLogger) Info(_ context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		l.log().Info(fmt.Sprintf(msg, data...), zlog.Any("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *gormLogger) Warn(_ context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		l.log().Warn(fmt.Sprintf(msg, data...), zlog.Any("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *gormLogger) Error(_ context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		l.log().Error(fmt.Sprintf(msg, data...), zlog.Any("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *gormLogger) Trace(_ context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	log := l.log()
+	fields := func() []zlog.Field {
+		sql, rows := fc()
+		return []zlog.Field{
+			zlog.Any("sql", sql),
+			zlog.Any("rows", rows),
+			zlog.Any("elapsed", elapsed),
+			zlog.Any("source", utils.FileWithLineNum()),
+		}
+	}
+	switch {
+	case err != nil && l.level >= logger.Error && !goerrors.Is(err, gorm.ErrRecordNotFound):
+		log.Error("query failed", append(fields(), zlog.Error(err))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		log.Warn("slow query", append(fields(), zlog.Any("threshold", l.slowThreshold))...)
+	case l.level >= logger.Info && log.Core().Enabled(zlog.DebugLevel):
+		log.Debug("query", fields()...)
+	}
+}
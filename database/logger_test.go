@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+)
+
+func TestGormLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := newGormLogger(func() *zap.Logger { return zap.New(core) }, 100*time.Millisecond)
+	fc := func() (string, int64) { return "SELECT 1", 1 }
+
+	// debug queries are dropped at info level
+	l.Trace(context.Background(), time.Now(), fc, nil)
+	assert.Equal(t, 0, logs.Len())
+
+	l.Trace(context.Background(), time.Now(), fc, gorm.ErrRecordNotFound)
+	assert.Equal(t, 0, logs.Len())
+
+	l.Trace(context.Background(), time.Now().Add(-time.Second), fc, nil)
+	l.Trace(context.Background(), time.Now(), fc, errors.New("boom"))
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, "SELECT 1", entries[0].ContextMap()["sql"])
+	assert.Equal(t, int64(1), entries[0].ContextMap()["rows"])
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	assert.Equal(t, "boom", entries[1].ContextMap()["error"])
+
+	l.LogMode(logger.Silent).Trace(context.Background(), time.Now(), fc, errors.New("boom"))
+	assert.Equal(t, 0, logs.Len())
+}
+
+func TestGormLoggerGlobal(t *testing.T) {
+	l := newGormLogger(globalGormLogger, 0)
+
+	// the global logger replaced after the creation is used
+	core, logs := observer.New(zapcore.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+	l.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 1 }, errors.New("boom"))
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "gorm", entries[0].ContextMap()["service"])
+}
@@ -1,48 +1,255 @@
 package database
 
 import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
 	"github.com/fiamma-chain/fiamma-go-sdk/spec"
 )
 
+// GetLpTransaction reads from a replica if any, use Primary to read your own writes
 func (db *Database) GetLpTransaction(txHash, btcAddress string) (*spec.LpTxInfo, error) {
+	return db.GetLpTransactionCtx(db.ctx(), txHash, btcAddress)
+}
+
+func (db *Database) GetLpTransactionCtx(ctx context.Context, txHash, btcAddress string) (*spec.LpTxInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 	var txInfo spec.LpTxInfo
-	if err := db.DB.Where("tx_hash = ? AND btc_address = ?", txHash, btcAddress).First(&txInfo).Error; err != nil {
-		return nil, err
+	if err := db.reader().WithContext(ctx).Where("tx_hash = ? AND btc_address = ?", txHash, btcAddress).First(&txInfo).Error; err != nil {
+		return nil, ctxError(ctx, err)
 	}
 	return &txInfo, nil
 }
 
 func (db *Database) CreateLpTransaction(tx *spec.LpTxInfo) error {
-	return db.DB.Create(tx).Error
+	return db.CreateLpTransactionCtx(db.ctx(), tx)
+}
+
+func (db *Database) CreateLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.DB.WithContext(ctx).Transaction(func(dbtx *gorm.DB) error {
+		return db.createLpTransaction(dbtx, tx)
+	})
+	return ctxError(ctx, err)
 }
 
 func (db *Database) CreateAndGetLpTransaction(tx *spec.LpTxInfo) (*spec.LpTxInfo, error) {
-	if err := db.DB.Create(tx).Error; err != nil {
-		return nil, err
-	}
+	return db.CreateAndGetLpTransactionCtx(db.ctx(), tx)
+}
+
+func (db *Database) CreateAndGetLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) (*spec.LpTxInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 	var txInfo spec.LpTxInfo
-	if err := db.DB.Where("tx_hash = ? AND btc_address = ?", tx.TxHash, tx.BtcAddress).First(&txInfo).Error; err != nil {
-		return nil, err
+	err := db.DB.WithContext(ctx).Transaction(func(dbtx *gorm.DB) error {
+		if err := db.createLpTransaction(dbtx, tx); err != nil {
+			return err
+		}
+		return dbtx.Where("tx_hash = ? AND btc_address = ?", tx.TxHash, tx.BtcAddress).First(&txInfo).Error
+	})
+	if err != nil {
+		return nil, ctxError(ctx, err)
 	}
 	return &txInfo, nil
 }
 
+// CreateOrGetLpTransaction creates the lp transaction unless its tx hash is already known,
+// in which case the existing one is returned. It reports whether the transaction was newly created,
+// and returns ErrLpTxPayloadConflict if the existing one differs in addresses or amount, or has been deleted.
+func (db *Database) CreateOrGetLpTransaction(tx *spec.LpTxInfo) (*spec.LpTxInfo, bool, error) {
+	return db.CreateOrGetLpTransactionCtx(db.ctx(), tx)
+}
+
+func (db *Database) CreateOrGetLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) (*spec.LpTxInfo, bool, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var txInfo spec.LpTxInfo
+	var created bool
+	err := db.DB.WithContext(ctx).Transaction(func(dbtx *gorm.DB) error {
+		res := dbtx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "tx_hash"}}, DoNothing: true}).Create(tx)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			created = true
+			if err := db.recordLpTxEvent(dbtx, &spec.LpTxEvent{
+				TxHash:  tx.TxHash,
+				Action:  spec.LpTxActionCreate,
+				ToState: tx.State,
+				After:   lpTxSnapshot(tx),
+			}); err != nil {
+				return err
+			}
+		}
+		if err := dbtx.Unscoped().Where("tx_hash = ?", tx.TxHash).First(&txInfo).Error; err != nil {
+			return err
+		}
+		if !created && !sameLpTxPayload(&txInfo, tx) {
+			return ErrLpTxPayloadConflict
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, ctxError(ctx, err)
+	}
+	return &txInfo, created, nil
+}
+
+// sameLpTxPayload compares the reported payload with the stored one, addresses are case-insensitive like citext
+func sameLpTxPayload(stored, reported *spec.LpTxInfo) bool {
+	return !stored.DeletedAt.Valid &&
+		strings.EqualFold(stored.BtcAddress, reported.BtcAddress) &&
+		strings.EqualFold(stored.EvmAddress, reported.EvmAddress) &&
+		stored.Amount.Equal(reported.Amount.Round(6))
+}
+
+func (db *Database) createLpTransaction(dbtx *gorm.DB, tx *spec.LpTxInfo) error {
+	if err := dbtx.Create(tx).Error; err != nil {
+		return duplicatedKeyError(err)
+	}
+	return db.recordLpTxEvent(dbtx, &spec.LpTxEvent{
+		TxHash:  tx.TxHash,
+		Action:  spec.LpTxActionCreate,
+		ToState: tx.State,
+		After:   lpTxSnapshot(tx),
+	})
+}
+
+// ListLpTransactionByAddress reads from a replica if any, use Primary to read your own writes
 func (db *Database) ListLpTransactionByAddress(btcAddress string) (*[]spec.LpTxInfo, error) {
+	return db.ListLpTransactionByAddressCtx(db.ctx(), btcAddress)
+}
+
+func (db *Database) ListLpTransactionByAddressCtx(ctx context.Context, btcAddress string) (*[]spec.LpTxInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 	var txInfos []spec.LpTxInfo
-	if err := db.DB.Where("btc_address = ?", btcAddress).Find(&txInfos).Error; err != nil {
-		return nil, err
+	if err := db.reader().WithContext(ctx).Where("btc_address = ?", btcAddress).Find(&txInfos).Error; err != nil {
+		return nil, ctxError(ctx, err)
 	}
 	return &txInfos, nil
 }
 
+// UpdateLpTransaction saves all fields except the state, which can only be changed by TransitionLpTransaction.
+// The update is conditional on tx.Version, ErrLpTxVersionConflict is returned if the row has been
+// modified since it was read, otherwise tx.Version is increased.
+// A tx without ID is never created, gorm.ErrRecordNotFound is returned instead.
 func (db *Database) UpdateLpTransaction(tx *spec.LpTxInfo) error {
-	return db.DB.Save(tx).Error
+	return db.UpdateLpTransactionCtx(db.ctx(), tx)
 }
 
-func (db *Database) DeleteLpTransaction(txHash, btcAddress string) error {
-	var txInfo spec.LpTxInfo
-	if err := db.DB.Where("tx_hash = ? AND btc_address = ?", txHash, btcAddress).Delete(&txInfo).Error; err != nil {
-		return err
+func (db *Database) UpdateLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) error {
+	if tx.ID == 0 {
+		return gorm.ErrRecordNotFound
 	}
-	return nil
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.DB.WithContext(ctx).Transaction(func(dbtx *gorm.DB) error {
+		var before spec.LpTxInfo
+		if err := dbtx.Where("id = ?", tx.ID).First(&before).Error; err != nil {
+			return err
+		}
+		if before.Version != tx.Version {
+			return ErrLpTxVersionConflict
+		}
+		now := time.Now()
+		res := dbtx.Model(&spec.LpTxInfo{}).Where("id = ? AND version = ?", tx.ID, tx.Version).Updates(map[string]interface{}{
+			"tx_hash":     tx.TxHash,
+			"btc_address": tx.BtcAddress,
+			"evm_address": tx.EvmAddress,
+			"amount":      tx.Amount,
+			"updated_at":  now,
+			"version":     gorm.Expr("version + 1"),
+		})
+		if res.Error != nil {
+			return duplicatedKeyError(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return ErrLpTxVersionConflict
+		}
+		tx.State = before.State
+		tx.UpdatedAt = now
+		tx.Version++
+		return db.recordLpTxEvent(dbtx, &spec.LpTxEvent{
+			TxHash:    tx.TxHash,
+			Action:    spec.LpTxActionUpdate,
+			FromState: before.State,
+			ToState:   before.State,
+			Before:    lpTxSnapshot(&before),
+			After:     lpTxSnapshot(tx),
+		})
+	})
+	return ctxError(ctx, err)
+}
+
+// TransitionLpTransaction moves the lp transaction from one state to another.
+// The update is conditional on the current state, so only one of several racing callers wins,
+// the others get ErrLpTxStateConflict.
+func (db *Database) TransitionLpTransaction(txHash, from, to string) error {
+	return db.TransitionLpTransactionCtx(db.ctx(), txHash, from, to)
+}
+
+func (db *Database) TransitionLpTransactionCtx(ctx context.Context, txHash, from, to string) error {
+	if !spec.CanTransitLpTxState(from, to) {
+		return ErrLpTxIllegalTransition
+	}
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.DB.WithContext(ctx).Transaction(func(dbtx *gorm.DB) error {
+		res := dbtx.Model(&spec.LpTxInfo{}).Where("tx_hash = ? AND state = ?", txHash, from).Updates(map[string]interface{}{
+			"state":   to,
+			"version": gorm.Expr("version + 1"),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			var txInfo spec.LpTxInfo
+			if err := dbtx.Select("id").Where("tx_hash = ?", txHash).First(&txInfo).Error; err != nil {
+				return err
+			}
+			return ErrLpTxStateConflict
+		}
+		return db.recordLpTxEvent(dbtx, &spec.LpTxEvent{
+			TxHash:    txHash,
+			Action:    spec.LpTxActionTransition,
+			FromState: from,
+			ToState:   to,
+		})
+	})
+	return ctxError(ctx, err)
+}
+
+func (db *Database) DeleteLpTransaction(txHash, btcAddress string) error {
+	return db.DeleteLpTransactionCtx(db.ctx(), txHash, btcAddress)
+}
+
+func (db *Database) DeleteLpTransactionCtx(ctx context.Context, txHash, btcAddress string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.DB.WithContext(ctx).Transaction(func(dbtx *gorm.DB) error {
+		var txInfo spec.LpTxInfo
+		if err := dbtx.Where("tx_hash = ? AND btc_address = ?", txHash, btcAddress).First(&txInfo).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+		if err := dbtx.Delete(&txInfo).Error; err != nil {
+			return err
+		}
+		return db.recordLpTxEvent(dbtx, &spec.LpTxEvent{
+			TxHash:    txHash,
+			Action:    spec.LpTxActionDelete,
+			FromState: txInfo.State,
+			Before:    lpTxSnapshot(&txInfo),
+		})
+	})
+	return ctxError(ctx, err)
 }
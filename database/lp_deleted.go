@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// DefaultPurgeBatchSize is the number of rows hard-deleted per batch if no batch size is given
+const DefaultPurgeBatchSize = 500
+
+// ListDeletedLpTransactions lists soft-deleted lp transactions matching the filter, newest first.
+// It returns the cursor of the next page, which is empty on the last page.
+func (db *Database) ListDeletedLpTransactions(filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	return db.ListDeletedLpTransactionsCtx(db.ctx(), filter, page)
+}
+
+func (db *Database) ListDeletedLpTransactionsCtx(ctx context.Context, filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	q := db.DB.WithContext(ctx).Unscoped().Model(&spec.LpTxInfo{}).Where("deleted_at IS NOT NULL")
+	txInfos, next, err := listLpTransactions(q, filter, page)
+	return txInfos, next, ctxError(ctx, err)
+}
+
+// RestoreLpTransaction reverses the soft deletion of an lp transaction,
+// gorm.ErrRecordNotFound is returned if there is no such deleted lp transaction
+func (db *Database) RestoreLpTransaction(txHash, btcAddress string) error {
+	return db.RestoreLpTransactionCtx(db.ctx(), txHash, btcAddress)
+}
+
+func (db *Database) RestoreLpTransactionCtx(ctx context.Context, txHash, btcAddress string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.DB.WithContext(ctx).Transaction(func(dbtx *gorm.DB) error {
+		var txInfo spec.LpTxInfo
+		res := dbtx.Unscoped().Model(&txInfo).Clauses(clause.Returning{}).
+			Where("tx_hash = ? AND btc_address = ? AND deleted_at IS NOT NULL", txHash, btcAddress).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"updated_at": time.Now(),
+				"version":    gorm.Expr("version + 1"),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return db.recordLpTxEvent(dbtx, &spec.LpTxEvent{
+			TxHash:  txHash,
+			Action:  spec.LpTxActionRestore,
+			ToState: txInfo.State,
+			After:   lpTxSnapshot(&txInfo),
+		})
+	})
+	return ctxError(ctx, err)
+}
+
+// PurgeLpTransactionsOlderThan hard-deletes the lp transactions soft-deleted for longer than age.
+// Rows are deleted in batches of batchSize, each batch in its own transaction, so that locks stay short.
+// It returns the number of purged rows, the audit events are kept and a purge event is appended.
+func (db *Database) PurgeLpTransactionsOlderThan(age time.Duration, batchSize int) (int64, error) {
+	return db.PurgeLpTransactionsOlderThanCtx(db.ctx(), age, batchSize)
+}
+
+func (db *Database) PurgeLpTransactionsOlderThanCtx(ctx context.Context, age time.Duration, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultPurgeBatchSize
+	}
+	before := time.Now().Add(-age)
+	var total int64
+	for {
+		n, err := db.purgeLpTransactionBatch(ctx, before, batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
+
+func (db *Database) purgeLpTransactionBatch(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var n int64
+	err := db.DB.WithContext(ctx).Transaction(func(dbtx *gorm.DB) error {
+		var txInfos []spec.LpTxInfo
+		err := dbtx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Order("id").Limit(batchSize).Find(&txInfos).Error
+		if err != nil || len(txInfos) == 0 {
+			return err
+		}
+		ids := make([]uint, 0, len(txInfos))
+		for _, txInfo := range txInfos {
+			ids = append(ids, txInfo.ID)
+		}
+		res := dbtx.Unscoped().Where("id IN ?", ids).Delete(&spec.LpTxInfo{})
+		if res.Error != nil {
+			return res.Error
+		}
+		for i := range txInfos {
+			if err := db.recordLpTxEvent(dbtx, &spec.LpTxEvent{
+				TxHash:    txInfos[i].TxHash,
+				Action:    spec.LpTxActionPurge,
+				FromState: txInfos[i].State,
+				Before:    lpTxSnapshot(&txInfos[i]),
+			}); err != nil {
+				return err
+			}
+		}
+		n = res.RowsAffected
+		return nil
+	})
+	return n, ctxError(ctx, err)
+}
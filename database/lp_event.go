@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// WithOperator returns a view of the database which records the operator in the audit events
+func (db *Database) WithOperator(operator string) *Database {
+	n := *db
+	n.operator = operator
+	return &n
+}
+
+// withoutOutbox returns a view of the database which records the audit events of the changes,
+// but does not announce them in the outbox, like backfills
+func (db *Database) withoutOutbox() *Database {
+	n := *db
+	n.noOutbox = true
+	return &n
+}
+
+// ListLpTransactionEvents returns the full audit timeline of an lp transaction, oldest first
+func (db *Database) ListLpTransactionEvents(txHash string) ([]spec.LpTxEvent, error) {
+	return db.ListLpTransactionEventsCtx(db.ctx(), txHash)
+}
+
+func (db *Database) ListLpTransactionEventsCtx(ctx context.Context, txHash string) ([]spec.LpTxEvent, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var events []spec.LpTxEvent
+	if err := db.DB.WithContext(ctx).Where("tx_hash = ?", txHash).Order("id ASC").Find(&events).Error; err != nil {
+		return nil, ctxError(ctx, err)
+	}
+	return events, nil
+}
+
+// recordLpTxEvent records the audit event, and enqueues it in the outbox if the change moved
+// the lp transaction into success, unless the view is withoutOutbox. tx must be the transaction of the change.
+func (db *Database) recordLpTxEvent(tx *gorm.DB, event *spec.LpTxEvent) error {
+	event.Operator = db.operator
+	if err := tx.Create(event).Error; err != nil {
+		return err
+	}
+	if db.noOutbox {
+		return nil
+	}
+	msg, err := lpTxSuccessMessage(event)
+	if err != nil || msg == nil {
+		return err
+	}
+	return enqueueOutboxMessage(tx, msg)
+}
+
+// lpTxSuccessMessage returns the outbox message announcing the event, nil unless the event
+// creates an lp transaction in success or transitions one into success
+func lpTxSuccessMessage(event *spec.LpTxEvent) (*spec.OutboxMessage, error) {
+	if event.ToState != spec.LpTxStateSuccess || event.FromState == spec.LpTxStateSuccess {
+		return nil, nil
+	}
+	if event.Action != spec.LpTxActionCreate && event.Action != spec.LpTxActionTransition {
+		return nil, nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.OutboxMessage{
+		Topic:   spec.OutboxTopicLpTxEvent,
+		Key:     event.TxHash,
+		Payload: string(payload),
+	}, nil
+}
+
+func lpTxSnapshot(txInfo *spec.LpTxInfo) string {
+	if txInfo == nil {
+		return ""
+	}
+	data, _ := json.Marshal(txInfo)
+	return string(data)
+}
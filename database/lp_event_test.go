@@ -0,0 +1,41 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+func TestLpTxSuccessMessage(t *testing.T) {
+	for _, event := range []spec.LpTxEvent{
+		{Action: spec.LpTxActionCreate, ToState: spec.LpTxStateCreated},
+		{Action: spec.LpTxActionTransition, FromState: spec.LpTxStateCreated, ToState: spec.LpTxStatePending},
+		{Action: spec.LpTxActionTransition, FromState: spec.LpTxStateProcessing, ToState: spec.LpTxStateInvalid},
+		{Action: spec.LpTxActionUpdate, FromState: spec.LpTxStateSuccess, ToState: spec.LpTxStateSuccess},
+		{Action: spec.LpTxActionDelete, FromState: spec.LpTxStateSuccess},
+		{Action: spec.LpTxActionRestore, ToState: spec.LpTxStateSuccess},
+		{Action: spec.LpTxActionPurge, FromState: spec.LpTxStateSuccess},
+	} {
+		msg, err := lpTxSuccessMessage(&event)
+		assert.NoError(t, err)
+		assert.Nil(t, msg, "%s %s -> %s", event.Action, event.FromState, event.ToState)
+	}
+
+	for _, event := range []spec.LpTxEvent{
+		{TxHash: "h1", Action: spec.LpTxActionCreate, ToState: spec.LpTxStateSuccess},
+		{TxHash: "h1", Action: spec.LpTxActionTransition, FromState: spec.LpTxStateProcessing, ToState: spec.LpTxStateSuccess},
+	} {
+		msg, err := lpTxSuccessMessage(&event)
+		assert.NoError(t, err)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, spec.OutboxTopicLpTxEvent, msg.Topic)
+			assert.Equal(t, "h1", msg.Key)
+			var payload spec.LpTxEvent
+			assert.NoError(t, json.Unmarshal([]byte(msg.Payload), &payload))
+			assert.Equal(t, event, payload)
+		}
+	}
+}
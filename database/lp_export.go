@@ -0,0 +1,370 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	goerrors "errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/ginctx"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	// DefaultImportBatchSize is the number of records imported per transaction if no batch size is given
+	DefaultImportBatchSize = 500
+
+	// maxNDJSONLineSize bounds an ndjson record
+	maxNDJSONLineSize = 1 << 20
+)
+
+var lpTxCSVHeader = []string{"tx_hash", "btc_address", "evm_address", "amount", "state", "created_at", "updated_at"}
+
+// maxLpTxAmount is the exclusive upper bound of decimal(24,6)
+var maxLpTxAmount = decimal.New(1, 18)
+
+// ErrInvalidFormat indicates the format is neither csv nor ndjson
+var ErrInvalidFormat = errors.CodeError(ginctx.ErrRequestParamInvalid, "invalid format, must be csv or ndjson")
+
+// errDryRun rolls back a dry run batch
+var errDryRun = goerrors.New("dry run")
+
+// LpTxRecord is the exported form of an lp transaction, the amount keeps its 6 decimal places
+type LpTxRecord struct {
+	TxHash     string    `json:"txHash"`
+	BtcAddress string    `json:"btcAddress"`
+	EvmAddress string    `json:"evmAddress"`
+	Amount     string    `json:"amount"`
+	State      string    `json:"state"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ImportOptions import options
+type ImportOptions struct {
+	// DryRun validates and imports every record, but rolls all changes back
+	DryRun    bool
+	BatchSize int
+}
+
+// ImportError is the error of a record, Line is 1-based and counts the csv header
+type ImportError struct {
+	Line   int    `json:"line"`
+	TxHash string `json:"txHash"`
+	Error  string `json:"error"`
+}
+
+// ImportResult counts the imported records, existing records with the same payload are skipped
+type ImportResult struct {
+	Total   int           `json:"total"`
+	Created int           `json:"created"`
+	Skipped int           `json:"skipped"`
+	Errors  []ImportError `json:"errors"`
+}
+
+// ExportLpTransactions streams the lp transactions matching the filter to w, oldest first.
+// It returns the number of exported records.
+func (db *Database) ExportLpTransactions(w io.Writer, format string, filter *spec.LpTxFilter) (int64, error) {
+	return db.ExportLpTransactionsCtx(db.ctx(), w, format, filter)
+}
+
+// ExportLpTransactionsCtx is not bounded by the default timeout, since exports may take long, use ctx instead
+func (db *Database) ExportLpTransactionsCtx(ctx context.Context, w io.Writer, format string, filter *spec.LpTxFilter) (int64, error) {
+	write, flush, err := newLpTxWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := applyLpTxFilter(db.DB.WithContext(ctx).Model(&spec.LpTxInfo{}), filter).Order("id").Rows()
+	if err != nil {
+		return 0, ctxError(ctx, err)
+	}
+	defer rows.Close()
+	var n int64
+	for rows.Next() {
+		var txInfo spec.LpTxInfo
+		if err = db.DB.ScanRows(rows, &txInfo); err != nil {
+			return n, ctxError(ctx, err)
+		}
+		if err = write(newLpTxRecord(&txInfo)); err != nil {
+			return n, errors.Trace(err)
+		}
+		n++
+	}
+	if err = rows.Err(); err != nil {
+		return n, ctxError(ctx, err)
+	}
+	return n, errors.Trace(flush())
+}
+
+// ImportLpTransactions validates and imports the records read from r, see ImportLpTransactionsCtx
+func (db *Database) ImportLpTransactions(r io.Reader, format string, opts ImportOptions) (*ImportResult, error) {
+	return db.ImportLpTransactionsCtx(db.ctx(), r, format, opts)
+}
+
+// ImportLpTransactionsCtx validates and imports the records read from r, keeping their state and timestamps.
+// Records are imported in batches, each batch in its own transaction. Invalid records and records
+// conflicting with existing ones are reported in the result and do not stop the import.
+// Imported records are audited, but never enqueued in the outbox.
+func (db *Database) ImportLpTransactionsCtx(ctx context.Context, r io.Reader, format string, opts ImportOptions) (*ImportResult, error) {
+	var read func() (*LpTxRecord, error)
+	line := 0
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		line++
+		index := map[string]int{}
+		for i, h := range header {
+			index[strings.TrimSpace(h)] = i
+		}
+		for _, h := range lpTxCSVHeader {
+			if _, ok := index[h]; !ok {
+				return nil, errors.CodeError(ginctx.ErrRequestParamInvalid, "missing csv column: "+h)
+			}
+		}
+		read = func() (*LpTxRecord, error) {
+			fields, err := cr.Read()
+			if pe, ok := err.(*csv.ParseError); ok {
+				line = pe.Line
+				return nil, &malformedRecordError{err}
+			}
+			if err != nil {
+				return nil, err
+			}
+			line++
+			return parseLpTxCSV(fields, index)
+		}
+	case FormatNDJSON:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, maxNDJSONLineSize)
+		read = func() (*LpTxRecord, error) {
+			for sc.Scan() {
+				line++
+				data := bytes.TrimSpace(sc.Bytes())
+				if len(data) == 0 {
+					continue
+				}
+				var rec LpTxRecord
+				if err := json.Unmarshal(data, &rec); err != nil {
+					return nil, &malformedRecordError{err}
+				}
+				return &rec, nil
+			}
+			if err := sc.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+	default:
+		return nil, ErrInvalidFormat
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultImportBatchSize
+	}
+	res := &ImportResult{}
+	type item struct {
+		line int
+		tx   *spec.LpTxInfo
+	}
+	var batch []item
+	// dryRunCreated keeps the records created by the rolled back batches of a dry run,
+	// so that the following batches see them as if they were committed
+	dryRunCreated := map[string]*spec.LpTxInfo{}
+	// imports are backfills, they are audited but never announced downstream
+	importer := db.withoutOutbox()
+	commit := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var created, skipped int
+		var errs []ImportError
+		var newTxs []*spec.LpTxInfo
+		err := importer.WithTx(ctx, func(tx Store) error {
+			for _, it := range batch {
+				var isNew bool
+				var err error
+				if prev, ok := dryRunCreated[it.tx.TxHash]; ok {
+					if !sameLpTxPayload(prev, it.tx) {
+						err = ErrLpTxPayloadConflict
+					}
+				} else {
+					_, isNew, err = tx.CreateOrGetLpTransactionCtx(ctx, it.tx)
+				}
+				switch {
+				case err == nil && isNew:
+					created++
+					newTxs = append(newTxs, it.tx)
+				case err == nil:
+					skipped++
+				case err == ErrLpTxPayloadConflict:
+					errs = append(errs, ImportError{Line: it.line, TxHash: it.tx.TxHash, Error: err.Error()})
+				default:
+					return err
+				}
+			}
+			if opts.DryRun {
+				return errDryRun
+			}
+			return nil
+		})
+		if err != nil && err != errDryRun {
+			return err
+		}
+		if opts.DryRun {
+			for _, tx := range newTxs {
+				dryRunCreated[tx.TxHash] = tx
+			}
+		}
+		res.Created += created
+		res.Skipped += skipped
+		res.Errors = append(res.Errors, errs...)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		rec, err := read()
+		if err == io.EOF {
+			break
+		}
+		res.Total++
+		var tx *spec.LpTxInfo
+		if err == nil {
+			tx, err = rec.toLpTxInfo()
+		}
+		if err != nil {
+			// only malformed records can be skipped, the reader cannot recover from other errors
+			if _, ok := err.(*malformedRecordError); !ok && rec == nil {
+				return res, errors.Trace(err)
+			}
+			res.Errors = append(res.Errors, ImportError{Line: line, TxHash: recTxHash(rec), Error: err.Error()})
+			continue
+		}
+		batch = append(batch, item{line: line, tx: tx})
+		if len(batch) >= opts.BatchSize {
+			if err = commit(); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, commit()
+}
+
+// malformedRecordError is the error of a record which cannot be parsed, the following records can still be read
+type malformedRecordError struct {
+	err error
+}
+
+func (e *malformedRecordError) Error() string {
+	return e.err.Error()
+}
+
+// newLpTxWriter returns the record writer of the format, flush must be called once all records are written
+func newLpTxWriter(w io.Writer, format string) (func(r *LpTxRecord) error, func() error, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(lpTxCSVHeader); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		write := func(r *LpTxRecord) error {
+			return cw.Write([]string{
+				r.TxHash, r.BtcAddress, r.EvmAddress, r.Amount, r.State,
+				r.CreatedAt.Format(time.RFC3339Nano), r.UpdatedAt.Format(time.RFC3339Nano),
+			})
+		}
+		flush := func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+		return write, flush, nil
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		write := func(r *LpTxRecord) error {
+			return enc.Encode(r)
+		}
+		return write, bw.Flush, nil
+	default:
+		return nil, nil, ErrInvalidFormat
+	}
+}
+
+func newLpTxRecord(tx *spec.LpTxInfo) *LpTxRecord {
+	return &LpTxRecord{
+		TxHash:     tx.TxHash,
+		BtcAddress: tx.BtcAddress,
+		EvmAddress: tx.EvmAddress,
+		Amount:     tx.Amount.StringFixed(6),
+		State:      tx.State,
+		CreatedAt:  tx.CreatedAt,
+		UpdatedAt:  tx.UpdatedAt,
+	}
+}
+
+func parseLpTxCSV(fields []string, index map[string]int) (*LpTxRecord, error) {
+	rec := &LpTxRecord{
+		TxHash:     fields[index["tx_hash"]],
+		BtcAddress: fields[index["btc_address"]],
+		EvmAddress: fields[index["evm_address"]],
+		Amount:     fields[index["amount"]],
+		State:      fields[index["state"]],
+	}
+	var err error
+	if rec.CreatedAt, err = time.Parse(time.RFC3339Nano, fields[index["created_at"]]); err != nil {
+		return rec, errors.Errorf("invalid created_at: %s", err.Error())
+	}
+	if rec.UpdatedAt, err = time.Parse(time.RFC3339Nano, fields[index["updated_at"]]); err != nil {
+		return rec, errors.Errorf("invalid updated_at: %s", err.Error())
+	}
+	return rec, nil
+}
+
+// toLpTxInfo validates the record
+func (r *LpTxRecord) toLpTxInfo() (*spec.LpTxInfo, error) {
+	if r.TxHash == "" || r.BtcAddress == "" || r.EvmAddress == "" {
+		return nil, errors.New("tx hash, btc address and evm address are required")
+	}
+	amount, err := decimal.NewFromString(r.Amount)
+	if err != nil {
+		return nil, errors.Errorf("invalid amount: %s", err.Error())
+	}
+	if amount.IsNegative() || amount.Cmp(maxLpTxAmount) >= 0 || !amount.Round(6).Equal(amount) {
+		return nil, errors.Errorf("invalid amount %s, must fit decimal(24,6)", r.Amount)
+	}
+	if !spec.IsValidLpTxState(r.State) {
+		return nil, errors.Errorf("invalid state: %s", r.State)
+	}
+	return &spec.LpTxInfo{
+		Model:      gorm.Model{CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt},
+		TxHash:     r.TxHash,
+		BtcAddress: r.BtcAddress,
+		EvmAddress: r.EvmAddress,
+		Amount:     amount,
+		State:      r.State,
+	}, nil
+}
+
+func recTxHash(r *LpTxRecord) string {
+	if r == nil {
+		return ""
+	}
+	return r.TxHash
+}
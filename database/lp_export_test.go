@@ -0,0 +1,187 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	goerrors "errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+func TestLpTxWriter(t *testing.T) {
+	created := time.Date(2024, 11, 11, 8, 0, 0, 123, time.UTC)
+	rec := newLpTxRecord(&spec.LpTxInfo{
+		Model:      gorm.Model{CreatedAt: created, UpdatedAt: created},
+		TxHash:     "h1",
+		BtcAddress: "bc1q",
+		EvmAddress: "0xab",
+		Amount:     decimal.RequireFromString("1.5"),
+		State:      spec.LpTxStateCreated,
+	})
+	assert.Equal(t, "1.500000", rec.Amount)
+
+	var buf bytes.Buffer
+	write, flush, err := newLpTxWriter(&buf, FormatCSV)
+	assert.NoError(t, err)
+	assert.NoError(t, write(rec))
+	assert.NoError(t, flush())
+	assert.Equal(t, "tx_hash,btc_address,evm_address,amount,state,created_at,updated_at\n"+
+		"h1,bc1q,0xab,1.500000,created,2024-11-11T08:00:00.000000123Z,2024-11-11T08:00:00.000000123Z\n", buf.String())
+
+	buf.Reset()
+	write, flush, err = newLpTxWriter(&buf, FormatNDJSON)
+	assert.NoError(t, err)
+	assert.NoError(t, write(rec))
+	assert.NoError(t, write(rec))
+	assert.NoError(t, flush())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"amount":"1.500000"`)
+
+	_, _, err = newLpTxWriter(&buf, "xml")
+	assert.Equal(t, ErrInvalidFormat, err)
+}
+
+func TestParseLpTxCSV(t *testing.T) {
+	index := map[string]int{}
+	for i, h := range lpTxCSVHeader {
+		index[h] = i
+	}
+	rec, err := parseLpTxCSV([]string{"h1", "bc1q", "0xab", "2.000001", "success", "2024-11-11T08:00:00Z", "2024-11-12T08:00:00Z"}, index)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.000001", rec.Amount)
+	assert.Equal(t, time.Date(2024, 11, 12, 8, 0, 0, 0, time.UTC), rec.UpdatedAt)
+
+	rec, err = parseLpTxCSV([]string{"h1", "bc1q", "0xab", "2", "success", "yesterday", "2024-11-12T08:00:00Z"}, index)
+	assert.Error(t, err)
+	assert.Equal(t, "h1", rec.TxHash)
+}
+
+func TestLpTxRecordValidation(t *testing.T) {
+	valid := LpTxRecord{TxHash: "h1", BtcAddress: "bc1q", EvmAddress: "0xab", Amount: "10.25", State: spec.LpTxStatePending}
+	tx, err := valid.toLpTxInfo()
+	assert.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("10.25").Equal(tx.Amount))
+	assert.Equal(t, spec.LpTxStatePending, tx.State)
+
+	cases := map[string]func(r *LpTxRecord){
+		"missing hash":      func(r *LpTxRecord) { r.TxHash = "" },
+		"missing address":   func(r *LpTxRecord) { r.EvmAddress = "" },
+		"malformed amount":  func(r *LpTxRecord) { r.Amount = "1,5" },
+		"negative amount":   func(r *LpTxRecord) { r.Amount = "-1" },
+		"too many decimals": func(r *LpTxRecord) { r.Amount = "0.0000001" },
+		"too large amount":  func(r *LpTxRecord) { r.Amount = "1000000000000000000" },
+		"unknown state":     func(r *LpTxRecord) { r.State = "done" },
+	}
+	for name, mutate := range cases {
+		r := valid
+		mutate(&r)
+		_, err = r.toLpTxInfo()
+		assert.Error(t, err, name)
+	}
+}
+
+type failingReader struct {
+	data io.Reader
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+	return n, err
+}
+
+func TestImportLpTransactionsReadError(t *testing.T) {
+	db := &Database{}
+	ioErr := goerrors.New("connection reset")
+	header := strings.Join(lpTxCSVHeader, ",") + "\n"
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res, err := db.ImportLpTransactionsCtx(context.Background(), &failingReader{strings.NewReader(header), ioErr}, FormatCSV, ImportOptions{})
+		assert.Equal(t, ioErr, errors.Cause(err))
+		assert.Empty(t, res.Errors)
+
+		_, err = db.ImportLpTransactionsCtx(context.Background(), &failingReader{strings.NewReader(`{"txHash":"h1"}`), ioErr}, FormatNDJSON, ImportOptions{})
+		assert.Error(t, err)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("import does not return on read errors")
+	}
+}
+
+func TestImportLpTransactionsInvalidRecords(t *testing.T) {
+	db := &Database{}
+	data := strings.Join(lpTxCSVHeader, ",") + "\n" +
+		"h1,bc1q,0xab,1,done,2024-11-11T08:00:00Z,2024-11-11T08:00:00Z\n" +
+		"h2,bc1q\n" +
+		"h3,bc1q,0xab,1,created,now,2024-11-11T08:00:00Z\n"
+	res, err := db.ImportLpTransactionsCtx(context.Background(), strings.NewReader(data), FormatCSV, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Total)
+	assert.Equal(t, 0, res.Created)
+	assert.Len(t, res.Errors, 3)
+	assert.Equal(t, []int{2, 3, 4}, []int{res.Errors[0].Line, res.Errors[1].Line, res.Errors[2].Line})
+	assert.Equal(t, "h3", res.Errors[2].TxHash)
+}
+
+func TestImportLpTransactionsInvalidNDJSON(t *testing.T) {
+	db := &Database{}
+	data := `{"txHash":"h1","btcAddress":"bc1q","evmAddress":"0xab","amount":1,"state":"created"}` + "\n" +
+		"\n" +
+		`{"txHash":"h2",` + "\n" +
+		`{"txHash":"h3","btcAddress":"bc1q","evmAddress":"0xab","amount":"1","state":"done"}` + "\n"
+	res, err := db.ImportLpTransactionsCtx(context.Background(), strings.NewReader(data), FormatNDJSON, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Total)
+	assert.Equal(t, 0, res.Created)
+	assert.Len(t, res.Errors, 3)
+	assert.Equal(t, []int{1, 3, 4}, []int{res.Errors[0].Line, res.Errors[1].Line, res.Errors[2].Line})
+	assert.Equal(t, "h3", res.Errors[2].TxHash)
+}
+
+func TestImportLpTransactionsDryRun(t *testing.T) {
+	db := testDB(t)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	data := strings.Join(lpTxCSVHeader, ",") + "\n" +
+		"h1-" + suffix + ",bc1q,0xab,1,success,2024-11-11T08:00:00Z,2024-11-11T08:00:00Z\n" +
+		"h1-" + suffix + ",bc1q,0xab,1,success,2024-11-11T08:00:00Z,2024-11-11T08:00:00Z\n" +
+		"h1-" + suffix + ",bc1q,0xab,2,success,2024-11-11T08:00:00Z,2024-11-11T08:00:00Z\n"
+
+	// every record is in its own batch, the dry run must still see the first one
+	res, err := db.ImportLpTransactions(strings.NewReader(data), FormatCSV, ImportOptions{DryRun: true, BatchSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Created)
+	assert.Equal(t, 1, res.Skipped)
+	if assert.Len(t, res.Errors, 1) {
+		assert.Equal(t, 4, res.Errors[0].Line)
+	}
+	_, err = db.GetLpTransaction("h1-"+suffix, "bc1q")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	res, err = db.ImportLpTransactions(strings.NewReader(data), FormatCSV, ImportOptions{BatchSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Created)
+	assert.Equal(t, 1, res.Skipped)
+	assert.Len(t, res.Errors, 1)
+	// imports are not announced downstream
+	var n int64
+	assert.NoError(t, db.DB.Model(&spec.OutboxMessage{}).Where("key = ?", "h1-"+suffix).Count(&n).Error)
+	assert.Equal(t, int64(0), n)
+}
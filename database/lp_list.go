@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// ListLpTransactions lists lp transactions matching the filter, newest first.
+// It returns the cursor of the next page, which is empty on the last page.
+func (db *Database) ListLpTransactions(filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	return db.ListLpTransactionsCtx(db.ctx(), filter, page)
+}
+
+func (db *Database) ListLpTransactionsCtx(ctx context.Context, filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	txInfos, next, err := listLpTransactions(db.DB.WithContext(ctx).Model(&spec.LpTxInfo{}), filter, page)
+	return txInfos, next, ctxError(ctx, err)
+}
+
+func listLpTransactions(q *gorm.DB, filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	limit := page.GetLimit()
+	q = applyLpTxFilter(q, filter)
+	if page != nil && page.Cursor != "" {
+		id, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		q = q.Where("id < ?", id)
+	}
+	var txInfos []spec.LpTxInfo
+	if err := q.Order("id DESC").Limit(limit + 1).Find(&txInfos).Error; err != nil {
+		return nil, "", err
+	}
+	var next string
+	if len(txInfos) > limit {
+		txInfos = txInfos[:limit]
+		next = encodeCursor(txInfos[limit-1].ID)
+	}
+	return txInfos, next, nil
+}
+
+func applyLpTxFilter(q *gorm.DB, f *spec.LpTxFilter) *gorm.DB {
+	if f == nil {
+		return q
+	}
+	if f.BtcAddress != "" {
+		q = q.Where("btc_address = ?", f.BtcAddress)
+	}
+	if f.EvmAddress != "" {
+		q = q.Where("evm_address = ?", f.EvmAddress)
+	}
+	if len(f.States) > 0 {
+		q = q.Where("state IN ?", f.States)
+	}
+	if f.MinAmount != nil {
+		q = q.Where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		q = q.Where("amount <= ?", *f.MaxAmount)
+	}
+	if f.CreatedAfter != nil {
+		q = q.Where("created_at >= ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		q = q.Where("created_at < ?", *f.CreatedBefore)
+	}
+	return q
+}
+
+func encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(string(data), 10, 64)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
+}
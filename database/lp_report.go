@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// amountTotal keeps the 6 decimal places of the amount column, sums never lose precision
+const amountTotal = "CAST(COALESCE(SUM(amount), 0) AS decimal(38,6)) AS amount"
+
+var lpTxIntervals = map[string]bool{
+	spec.IntervalHour:  true,
+	spec.IntervalDay:   true,
+	spec.IntervalWeek:  true,
+	spec.IntervalMonth: true,
+}
+
+// SumLpTransactionsByState returns the count and amount total per state of the lp transactions
+// matching the filter, set filter.BtcAddress for per-address totals
+func (db *Database) SumLpTransactionsByState(filter *spec.LpTxFilter) ([]spec.LpTxStateTotal, error) {
+	return db.SumLpTransactionsByStateCtx(db.ctx(), filter)
+}
+
+func (db *Database) SumLpTransactionsByStateCtx(ctx context.Context, filter *spec.LpTxFilter) ([]spec.LpTxStateTotal, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var totals []spec.LpTxStateTotal
+	err := lpTxStateTotalsQuery(db.DB.WithContext(ctx), filter).Scan(&totals).Error
+	if err != nil {
+		return nil, ctxError(ctx, err)
+	}
+	return totals, nil
+}
+
+// SumLpTransactionsByInterval returns the count and amount total of the lp transactions matching the filter,
+// bucketed by creation time in UTC. The interval is one of hour, day, week and month, empty buckets are omitted.
+func (db *Database) SumLpTransactionsByInterval(filter *spec.LpTxFilter, interval string) ([]spec.LpTxBucket, error) {
+	return db.SumLpTransactionsByIntervalCtx(db.ctx(), filter, interval)
+}
+
+func (db *Database) SumLpTransactionsByIntervalCtx(ctx context.Context, filter *spec.LpTxFilter, interval string) ([]spec.LpTxBucket, error) {
+	start, err := lpTxBucketStart(interval)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var buckets []spec.LpTxBucket
+	err = lpTxBucketsQuery(db.DB.WithContext(ctx), filter, start).Scan(&buckets).Error
+	if err != nil {
+		return nil, ctxError(ctx, err)
+	}
+	for i := range buckets {
+		buckets[i].Start = buckets[i].Start.UTC()
+	}
+	return buckets, nil
+}
+
+// lpTxBucketStart returns the expression truncating the creation time to the start of its bucket in UTC
+func lpTxBucketStart(interval string) (string, error) {
+	if !lpTxIntervals[interval] {
+		return "", ErrInvalidInterval
+	}
+	// the interval is one of the known units, it is safe to inline
+	return "date_trunc('" + interval + "', created_at AT TIME ZONE 'UTC')", nil
+}
+
+// TopLpDepositors returns the btc addresses with the largest amount total of the lp transactions matching the filter
+func (db *Database) TopLpDepositors(filter *spec.LpTxFilter, limit int) ([]spec.LpTxDepositor, error) {
+	return db.TopLpDepositorsCtx(db.ctx(), filter, limit)
+}
+
+func (db *Database) TopLpDepositorsCtx(ctx context.Context, filter *spec.LpTxFilter, limit int) ([]spec.LpTxDepositor, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var depositors []spec.LpTxDepositor
+	err := lpTxDepositorsQuery(db.DB.WithContext(ctx), filter, limit).Scan(&depositors).Error
+	if err != nil {
+		return nil, ctxError(ctx, err)
+	}
+	return depositors, nil
+}
+
+func lpTxStateTotalsQuery(q *gorm.DB, filter *spec.LpTxFilter) *gorm.DB {
+	return applyLpTxFilter(q.Model(&spec.LpTxInfo{}), filter).
+		Select("state, COUNT(*) AS count, " + amountTotal).
+		Group("state").Order("state")
+}
+
+// lpTxBucketsQuery groups the lp transactions by the bucket start expression returned by lpTxBucketStart
+func lpTxBucketsQuery(q *gorm.DB, filter *spec.LpTxFilter, start string) *gorm.DB {
+	return applyLpTxFilter(q.Model(&spec.LpTxInfo{}), filter).
+		Select(start + " AS start, COUNT(*) AS count, " + amountTotal).
+		Group("start").Order("start")
+}
+
+func lpTxDepositorsQuery(q *gorm.DB, filter *spec.LpTxFilter, limit int) *gorm.DB {
+	return applyLpTxFilter(q.Model(&spec.LpTxInfo{}), filter).
+		Select("btc_address, COUNT(*) AS count, " + amountTotal).
+		Group("btc_address").Order("amount DESC, btc_address").
+		Limit((&spec.Page{Limit: limit}).GetLimit())
+}
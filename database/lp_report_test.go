@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// reportSQL returns the statement of a report query, generated without any database
+func reportSQL(t *testing.T, query func(q *gorm.DB) *gorm.DB) string {
+	g, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		Logger:               logger.Discard,
+		DisableAutomaticPing: true,
+	})
+	assert.NoError(t, err)
+	return g.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return query(tx).Find(&[]map[string]interface{}{})
+	})
+}
+
+func TestLpTxBucketStart(t *testing.T) {
+	for _, interval := range []string{spec.IntervalHour, spec.IntervalDay, spec.IntervalWeek, spec.IntervalMonth} {
+		expr, err := lpTxBucketStart(interval)
+		assert.NoError(t, err)
+		assert.Equal(t, "date_trunc('"+interval+"', created_at AT TIME ZONE 'UTC')", expr)
+	}
+	for _, interval := range []string{"", "minute", "DAY", "day'); DROP TABLE lp_tx_infos; --"} {
+		_, err := lpTxBucketStart(interval)
+		assert.Equal(t, ErrInvalidInterval, err)
+	}
+
+	_, err := (&Database{}).SumLpTransactionsByIntervalCtx(context.Background(), nil, "minute")
+	assert.Equal(t, ErrInvalidInterval, err)
+}
+
+func TestLpTxBucketsQuery(t *testing.T) {
+	start, err := lpTxBucketStart(spec.IntervalWeek)
+	assert.NoError(t, err)
+	sql := reportSQL(t, func(q *gorm.DB) *gorm.DB {
+		return lpTxBucketsQuery(q, &spec.LpTxFilter{BtcAddress: "bc1q"}, start)
+	})
+	assert.Equal(t, `SELECT date_trunc('week', created_at AT TIME ZONE 'UTC') AS start, COUNT(*) AS count, `+
+		`CAST(COALESCE(SUM(amount), 0) AS decimal(38,6)) AS amount FROM "lp_tx_infos" `+
+		`WHERE btc_address = 'bc1q' AND "lp_tx_infos"."deleted_at" IS NULL GROUP BY "start" ORDER BY start`, sql)
+}
+
+func TestLpTxStateTotalsQuery(t *testing.T) {
+	sql := reportSQL(t, func(q *gorm.DB) *gorm.DB {
+		return lpTxStateTotalsQuery(q, &spec.LpTxFilter{States: []string{"created"}})
+	})
+	assert.Equal(t, `SELECT state, COUNT(*) AS count, CAST(COALESCE(SUM(amount), 0) AS decimal(38,6)) AS amount `+
+		`FROM "lp_tx_infos" WHERE state IN ('created') AND "lp_tx_infos"."deleted_at" IS NULL GROUP BY "state" ORDER BY state`, sql)
+}
+
+func TestLpTxDepositorsQuery(t *testing.T) {
+	sql := reportSQL(t, func(q *gorm.DB) *gorm.DB {
+		return lpTxDepositorsQuery(q, nil, 0)
+	})
+	assert.Contains(t, sql, `GROUP BY "btc_address" ORDER BY amount DESC, btc_address LIMIT 20`)
+
+	sql = reportSQL(t, func(q *gorm.DB) *gorm.DB {
+		return lpTxDepositorsQuery(q, nil, 1000)
+	})
+	assert.Contains(t, sql, "LIMIT 100")
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// insertedID answers the inserts of gorm, which return the generated id
+func insertedID(id int64) fakeResult {
+	return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{id}}}
+}
+
+func TestTransitionLpTransactionSQL(t *testing.T) {
+	ctx := context.Background()
+
+	// the winner of the race moves the row and records the event
+	db, f := newFakeDatabase(t, func(query string, _ []interface{}) fakeResult {
+		switch {
+		case strings.HasPrefix(query, `UPDATE "lp_tx_infos"`):
+			return fakeResult{affected: 1}
+		case strings.HasPrefix(query, `INSERT INTO "lp_tx_events"`):
+			return insertedID(1)
+		}
+		return fakeResult{}
+	})
+	assert.NoError(t, db.TransitionLpTransactionCtx(ctx, "h1", spec.LpTxStateCreated, spec.LpTxStatePending))
+	f.assertStatements(t, "BEGIN", `UPDATE "lp_tx_infos"`, `INSERT INTO "lp_tx_events"`, "COMMIT")
+	update, _ := f.find(`UPDATE "lp_tx_infos"`)
+	assert.Contains(t, update.query, "WHERE (tx_hash = $3 AND state = $4)")
+	assert.Equal(t, []interface{}{"h1", spec.LpTxStateCreated}, update.args[2:])
+
+	// the loser updates nothing, and gets a conflict as the row exists
+	db, f = newFakeDatabase(t, func(query string, _ []interface{}) fakeResult {
+		if strings.HasPrefix(query, `SELECT "id" FROM "lp_tx_infos"`) {
+			return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}}}
+		}
+		return fakeResult{}
+	})
+	assert.Equal(t, ErrLpTxStateConflict, db.TransitionLpTransactionCtx(ctx, "h1", spec.LpTxStateCreated, spec.LpTxStatePending))
+	f.assertStatements(t, "BEGIN", `UPDATE "lp_tx_infos"`, `SELECT "id" FROM "lp_tx_infos"`, "ROLLBACK")
+
+	// a missing row is not a conflict
+	db, f = newFakeDatabase(t, nil)
+	assert.Equal(t, gorm.ErrRecordNotFound, db.TransitionLpTransactionCtx(ctx, "h1", spec.LpTxStateCreated, spec.LpTxStatePending))
+	f.assertStatements(t, "BEGIN", `UPDATE "lp_tx_infos"`, `SELECT "id" FROM "lp_tx_infos"`, "ROLLBACK")
+}
+
+func TestUpdateLpTransactionSQL(t *testing.T) {
+	ctx := context.Background()
+	tx := &spec.LpTxInfo{TxHash: "h1", BtcAddress: "bc1q", Amount: decimal.NewFromInt(1), Version: 3}
+	tx.ID = 1
+
+	// the row is read at the expected version, but another writer wins before the conditional update
+	db, f := newFakeDatabase(t, func(query string, _ []interface{}) fakeResult {
+		if strings.HasPrefix(query, `SELECT * FROM "lp_tx_infos"`) {
+			return fakeResult{columns: []string{"id", "tx_hash", "state", "version"}, rows: [][]driver.Value{{int64(1), "h1", spec.LpTxStateCreated, int64(3)}}}
+		}
+		return fakeResult{}
+	})
+	assert.Equal(t, ErrLpTxVersionConflict, db.UpdateLpTransactionCtx(ctx, tx))
+	assert.Equal(t, uint(3), tx.Version)
+	f.assertStatements(t, "BEGIN", `SELECT * FROM "lp_tx_infos"`, `UPDATE "lp_tx_infos"`, "ROLLBACK")
+	update, _ := f.find(`UPDATE "lp_tx_infos"`)
+	assert.Contains(t, update.query, `"version"=version + 1 WHERE (id = $6 AND version = $7)`)
+	assert.Equal(t, []interface{}{uint(1), uint(3)}, update.args[5:])
+
+	// a unique violation is reported like MemoryStore does
+	db, f = newFakeDatabase(t, func(query string, _ []interface{}) fakeResult {
+		switch {
+		case strings.HasPrefix(query, `SELECT * FROM "lp_tx_infos"`):
+			return fakeResult{columns: []string{"id", "version"}, rows: [][]driver.Value{{int64(1), int64(3)}}}
+		case strings.HasPrefix(query, `UPDATE "lp_tx_infos"`):
+			return fakeResult{err: &pgconn.PgError{Code: pgUniqueViolation}}
+		}
+		return fakeResult{}
+	})
+	assert.Equal(t, gorm.ErrDuplicatedKey, db.UpdateLpTransactionCtx(ctx, tx))
+	f.assertStatements(t, "BEGIN", `SELECT * FROM "lp_tx_infos"`, `UPDATE "lp_tx_infos"`, "ROLLBACK")
+
+	// an update never inserts
+	db, f = newFakeDatabase(t, nil)
+	assert.Equal(t, gorm.ErrRecordNotFound, db.UpdateLpTransactionCtx(ctx, &spec.LpTxInfo{TxHash: "h2"}))
+	f.assertStatements(t)
+}
+
+func TestCreateOrGetLpTransactionSQL(t *testing.T) {
+	ctx := context.Background()
+	reported := &spec.LpTxInfo{TxHash: "h1", BtcAddress: "bc1q", EvmAddress: "0xA", Amount: decimal.NewFromInt(1)}
+	stored := func(deletedAt interface{}) fakeResult {
+		return fakeResult{
+			columns: []string{"id", "tx_hash", "btc_address", "evm_address", "amount", "deleted_at"},
+			rows:    [][]driver.Value{{int64(7), "h1", "BC1Q", "0xa", "1.000000", deletedAt}},
+		}
+	}
+
+	// the insert does nothing on conflict, and the existing row is read including the deleted ones
+	db, f := newFakeDatabase(t, func(query string, _ []interface{}) fakeResult {
+		if strings.HasPrefix(query, `SELECT * FROM "lp_tx_infos"`) {
+			return stored(time.Now())
+		}
+		return fakeResult{}
+	})
+	_, _, err := db.CreateOrGetLpTransactionCtx(ctx, reported)
+	assert.Equal(t, ErrLpTxPayloadConflict, err)
+	f.assertStatements(t, "BEGIN", `INSERT INTO "lp_tx_infos"`, `SELECT * FROM "lp_tx_infos"`, "ROLLBACK")
+	insert, _ := f.find(`INSERT INTO "lp_tx_infos"`)
+	assert.Contains(t, insert.query, `ON CONFLICT ("tx_hash") DO NOTHING RETURNING "id"`)
+	read, _ := f.find(`SELECT * FROM "lp_tx_infos"`)
+	assert.NotContains(t, read.query, "deleted_at")
+
+	// the existing row with the same payload is returned
+	db, f = newFakeDatabase(t, func(query string, _ []interface{}) fakeResult {
+		if strings.HasPrefix(query, `SELECT * FROM "lp_tx_infos"`) {
+			return stored(nil)
+		}
+		return fakeResult{}
+	})
+	got, created, err := db.CreateOrGetLpTransactionCtx(ctx, reported)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, uint(7), got.ID)
+	f.assertStatements(t, "BEGIN", `INSERT INTO "lp_tx_infos"`, `SELECT * FROM "lp_tx_infos"`, "COMMIT")
+
+	// a new row is created with its event
+	db, f = newFakeDatabase(t, func(query string, _ []interface{}) fakeResult {
+		switch {
+		case strings.HasPrefix(query, `INSERT INTO "lp_tx_infos"`):
+			return insertedID(7)
+		case strings.HasPrefix(query, `INSERT INTO "lp_tx_events"`):
+			return insertedID(1)
+		case strings.HasPrefix(query, `SELECT * FROM "lp_tx_infos"`):
+			return stored(nil)
+		}
+		return fakeResult{}
+	})
+	got, created, err = db.CreateOrGetLpTransactionCtx(ctx, reported)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, uint(7), got.ID)
+	f.assertStatements(t, "BEGIN", `INSERT INTO "lp_tx_infos"`, `INSERT INTO "lp_tx_events"`, `SELECT * FROM "lp_tx_infos"`, "COMMIT")
+}
@@ -0,0 +1,565 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// MemoryStore is a thread-safe in-memory implementation of LpTxStore and PropertyStore for tests.
+// It mirrors the behaviours of Database: soft deletes, case-insensitive addresses,
+// gorm.ErrDuplicatedKey on duplicated tx hash and gorm.ErrRecordNotFound.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	lpTxs  []*spec.LpTxInfo // ordered by id, including soft deleted rows
+	events []spec.LpTxEvent
+	props  map[string]*spec.Property
+	lastID uint
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		props: map[string]*spec.Property{},
+	}
+}
+
+func (m *MemoryStore) GetLpTransaction(txHash, btcAddress string) (*spec.LpTxInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	row := m.findLpTx(txHash, btcAddress)
+	if row == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	txInfo := *row
+	return &txInfo, nil
+}
+
+func (m *MemoryStore) CreateLpTransaction(tx *spec.LpTxInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createLpTx(tx)
+}
+
+func (m *MemoryStore) CreateAndGetLpTransaction(tx *spec.LpTxInfo) (*spec.LpTxInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.createLpTx(tx); err != nil {
+		return nil, err
+	}
+	txInfo := *m.findLpTx(tx.TxHash, tx.BtcAddress)
+	return &txInfo, nil
+}
+
+func (m *MemoryStore) CreateOrGetLpTransaction(tx *spec.LpTxInfo) (*spec.LpTxInfo, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if row := m.findLpTxByHashUnscoped(tx.TxHash); row != nil {
+		if !sameLpTxPayload(row, tx) {
+			return nil, false, ErrLpTxPayloadConflict
+		}
+		txInfo := *row
+		return &txInfo, false, nil
+	}
+	if err := m.createLpTx(tx); err != nil {
+		return nil, false, err
+	}
+	txInfo := *m.findLpTxByHash(tx.TxHash)
+	return &txInfo, true, nil
+}
+
+func (m *MemoryStore) ListLpTransactionByAddress(btcAddress string) (*[]spec.LpTxInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	txInfos := []spec.LpTxInfo{}
+	for _, row := range m.lpTxs {
+		if !row.DeletedAt.Valid && strings.EqualFold(row.BtcAddress, btcAddress) {
+			txInfos = append(txInfos, *row)
+		}
+	}
+	return &txInfos, nil
+}
+
+func (m *MemoryStore) ListLpTransactions(filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	return m.listLpTransactions(false, filter, page)
+}
+
+func (m *MemoryStore) ListDeletedLpTransactions(filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	return m.listLpTransactions(true, filter, page)
+}
+
+func (m *MemoryStore) listLpTransactions(deleted bool, filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	limit := page.GetLimit()
+	var before uint
+	if page != nil && page.Cursor != "" {
+		id, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		before = id
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var txInfos []spec.LpTxInfo
+	for i := len(m.lpTxs) - 1; i >= 0 && len(txInfos) <= limit; i-- {
+		row := m.lpTxs[i]
+		if row.DeletedAt.Valid != deleted || (before != 0 && row.ID >= before) || !matchLpTxFilter(row, filter) {
+			continue
+		}
+		txInfos = append(txInfos, *row)
+	}
+	var next string
+	if len(txInfos) > limit {
+		txInfos = txInfos[:limit]
+		next = encodeCursor(txInfos[limit-1].ID)
+	}
+	return txInfos, next, nil
+}
+
+func (m *MemoryStore) UpdateLpTransaction(tx *spec.LpTxInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row := m.findLpTxByID(tx.ID)
+	if row == nil {
+		return gorm.ErrRecordNotFound
+	}
+	if row.Version != tx.Version {
+		return ErrLpTxVersionConflict
+	}
+	for _, other := range m.lpTxs {
+		if other.ID != row.ID && other.TxHash == tx.TxHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	before := *row
+	row.TxHash = tx.TxHash
+	row.BtcAddress = tx.BtcAddress
+	row.EvmAddress = tx.EvmAddress
+	row.Amount = tx.Amount.Round(6)
+	row.UpdatedAt = time.Now()
+	row.Version++
+	tx.State = row.State
+	tx.UpdatedAt = row.UpdatedAt
+	tx.Version = row.Version
+	m.appendEvent(spec.LpTxEvent{
+		TxHash:    row.TxHash,
+		Action:    spec.LpTxActionUpdate,
+		FromState: before.State,
+		ToState:   row.State,
+		Before:    lpTxSnapshot(&before),
+		After:     lpTxSnapshot(tx),
+	})
+	return nil
+}
+
+func (m *MemoryStore) TransitionLpTransaction(txHash, from, to string) error {
+	if !spec.CanTransitLpTxState(from, to) {
+		return ErrLpTxIllegalTransition
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row := m.findLpTxByHash(txHash)
+	if row == nil {
+		return gorm.ErrRecordNotFound
+	}
+	if row.State != from {
+		return ErrLpTxStateConflict
+	}
+	row.State = to
+	row.UpdatedAt = time.Now()
+	row.Version++
+	m.appendEvent(spec.LpTxEvent{
+		TxHash:    txHash,
+		Action:    spec.LpTxActionTransition,
+		FromState: from,
+		ToState:   to,
+	})
+	return nil
+}
+
+func (m *MemoryStore) DeleteLpTransaction(txHash, btcAddress string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row := m.findLpTx(txHash, btcAddress)
+	if row == nil {
+		return nil
+	}
+	row.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	m.appendEvent(spec.LpTxEvent{
+		TxHash:    txHash,
+		Action:    spec.LpTxActionDelete,
+		FromState: row.State,
+		Before:    lpTxSnapshot(row),
+	})
+	return nil
+}
+
+func (m *MemoryStore) RestoreLpTransaction(txHash, btcAddress string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row := m.findLpTxByHashUnscoped(txHash)
+	if row == nil || !row.DeletedAt.Valid || !strings.EqualFold(row.BtcAddress, btcAddress) {
+		return gorm.ErrRecordNotFound
+	}
+	row.DeletedAt = gorm.DeletedAt{}
+	row.UpdatedAt = time.Now()
+	row.Version++
+	m.appendEvent(spec.LpTxEvent{
+		TxHash:  txHash,
+		Action:  spec.LpTxActionRestore,
+		ToState: row.State,
+		After:   lpTxSnapshot(row),
+	})
+	return nil
+}
+
+func (m *MemoryStore) ListLpTransactionEvents(txHash string) ([]spec.LpTxEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var events []spec.LpTxEvent
+	for _, e := range m.events {
+		if e.TxHash == txHash {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *MemoryStore) GetProperty(name string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	prop, ok := m.props[name]
+	if !ok {
+		return "", gorm.ErrRecordNotFound
+	}
+	return prop.Value, nil
+}
+
+func (m *MemoryStore) UpdateProperty(prop *spec.Property) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.updateProperty(prop)
+	return err
+}
+
+func (m *MemoryStore) UpsertProperty(prop *spec.Property) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.upsertProperty(prop.Name, prop.Value)
+	return nil
+}
+
+func (m *MemoryStore) UpdateAndGetProperty(prop *spec.Property) (*spec.Property, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, err := m.updateProperty(prop)
+	if err != nil {
+		return nil, err
+	}
+	newProp := *old
+	return &newProp, nil
+}
+
+// SetProperty creates or replaces a property, it is used to seed the store
+func (m *MemoryStore) SetProperty(name, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.upsertProperty(name, value)
+}
+
+func (m *MemoryStore) updateProperty(prop *spec.Property) (*spec.Property, error) {
+	old, ok := m.props[prop.Name]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	old.Value = prop.Value
+	old.UpdatedAt = time.Now()
+	return old, nil
+}
+
+func (m *MemoryStore) upsertProperty(name, value string) {
+	now := time.Now()
+	if prop, ok := m.props[name]; ok {
+		prop.Value = value
+		prop.UpdatedAt = now
+		return
+	}
+	m.lastID++
+	m.props[name] = &spec.Property{
+		Model: gorm.Model{ID: m.lastID, CreatedAt: now, UpdatedAt: now},
+		Name:  name,
+		Value: value,
+	}
+}
+
+// WithTx runs fn against a copy of the store which replaces the store if fn succeeds.
+// Transactions are serialized, the store must not be used directly by fn, only through tx.
+func (m *MemoryStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tx := m.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	m.lpTxs, m.events, m.props, m.lastID = tx.lpTxs, tx.events, tx.props, tx.lastID
+	return nil
+}
+
+func (m *MemoryStore) clone() *MemoryStore {
+	n := &MemoryStore{
+		lpTxs:  make([]*spec.LpTxInfo, 0, len(m.lpTxs)),
+		events: append([]spec.LpTxEvent(nil), m.events...),
+		props:  make(map[string]*spec.Property, len(m.props)),
+		lastID: m.lastID,
+	}
+	for _, row := range m.lpTxs {
+		r := *row
+		n.lpTxs = append(n.lpTxs, &r)
+	}
+	for name, prop := range m.props {
+		p := *prop
+		n.props[name] = &p
+	}
+	return n
+}
+
+// The context variants only check the context before delegating, since memory calls never block
+
+func (m *MemoryStore) GetLpTransactionCtx(ctx context.Context, txHash, btcAddress string) (*spec.LpTxInfo, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+	return m.GetLpTransaction(txHash, btcAddress)
+}
+
+func (m *MemoryStore) CreateLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	return m.CreateLpTransaction(tx)
+}
+
+func (m *MemoryStore) CreateAndGetLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) (*spec.LpTxInfo, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+	return m.CreateAndGetLpTransaction(tx)
+}
+
+func (m *MemoryStore) CreateOrGetLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) (*spec.LpTxInfo, bool, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, false, err
+	}
+	return m.CreateOrGetLpTransaction(tx)
+}
+
+func (m *MemoryStore) ListLpTransactionByAddressCtx(ctx context.Context, btcAddress string) (*[]spec.LpTxInfo, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+	return m.ListLpTransactionByAddress(btcAddress)
+}
+
+func (m *MemoryStore) ListLpTransactionsCtx(ctx context.Context, filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, "", err
+	}
+	return m.ListLpTransactions(filter, page)
+}
+
+func (m *MemoryStore) UpdateLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	return m.UpdateLpTransaction(tx)
+}
+
+func (m *MemoryStore) TransitionLpTransactionCtx(ctx context.Context, txHash, from, to string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	return m.TransitionLpTransaction(txHash, from, to)
+}
+
+func (m *MemoryStore) DeleteLpTransactionCtx(ctx context.Context, txHash, btcAddress string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	return m.DeleteLpTransaction(txHash, btcAddress)
+}
+
+func (m *MemoryStore) ListDeletedLpTransactionsCtx(ctx context.Context, filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, "", err
+	}
+	return m.ListDeletedLpTransactions(filter, page)
+}
+
+func (m *MemoryStore) RestoreLpTransactionCtx(ctx context.Context, txHash, btcAddress string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	return m.RestoreLpTransaction(txHash, btcAddress)
+}
+
+func (m *MemoryStore) ListLpTransactionEventsCtx(ctx context.Context, txHash string) ([]spec.LpTxEvent, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+	return m.ListLpTransactionEvents(txHash)
+}
+
+func (m *MemoryStore) GetPropertyCtx(ctx context.Context, name string) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+	return m.GetProperty(name)
+}
+
+func (m *MemoryStore) UpdatePropertyCtx(ctx context.Context, prop *spec.Property) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	return m.UpdateProperty(prop)
+}
+
+func (m *MemoryStore) UpsertPropertyCtx(ctx context.Context, prop *spec.Property) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+	return m.UpsertProperty(prop)
+}
+
+func (m *MemoryStore) UpdateAndGetPropertyCtx(ctx context.Context, prop *spec.Property) (*spec.Property, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+	return m.UpdateAndGetProperty(prop)
+}
+
+func checkCtx(ctx context.Context) error {
+	return ctxError(ctx, ctx.Err())
+}
+
+func (m *MemoryStore) createLpTx(tx *spec.LpTxInfo) error {
+	if m.findLpTxByHashUnscoped(tx.TxHash) != nil {
+		return gorm.ErrDuplicatedKey
+	}
+	m.lastID++
+	now := time.Now()
+	tx.ID = m.lastID
+	if tx.CreatedAt.IsZero() {
+		tx.CreatedAt = now
+	}
+	if tx.UpdatedAt.IsZero() {
+		tx.UpdatedAt = now
+	}
+	if tx.State == "" {
+		tx.State = spec.LpTxStateCreated
+	}
+	if tx.Version == 0 {
+		tx.Version = 1
+	}
+	tx.Amount = tx.Amount.Round(6)
+	row := *tx
+	m.lpTxs = append(m.lpTxs, &row)
+	m.appendEvent(spec.LpTxEvent{
+		TxHash:  tx.TxHash,
+		Action:  spec.LpTxActionCreate,
+		ToState: tx.State,
+		After:   lpTxSnapshot(tx),
+	})
+	return nil
+}
+
+func (m *MemoryStore) appendEvent(e spec.LpTxEvent) {
+	m.lastID++
+	e.ID = m.lastID
+	e.CreatedAt = time.Now()
+	m.events = append(m.events, e)
+}
+
+func (m *MemoryStore) findLpTx(txHash, btcAddress string) *spec.LpTxInfo {
+	row := m.findLpTxByHash(txHash)
+	if row == nil || !strings.EqualFold(row.BtcAddress, btcAddress) {
+		return nil
+	}
+	return row
+}
+
+func (m *MemoryStore) findLpTxByHash(txHash string) *spec.LpTxInfo {
+	row := m.findLpTxByHashUnscoped(txHash)
+	if row == nil || row.DeletedAt.Valid {
+		return nil
+	}
+	return row
+}
+
+func (m *MemoryStore) findLpTxByHashUnscoped(txHash string) *spec.LpTxInfo {
+	for _, row := range m.lpTxs {
+		if row.TxHash == txHash {
+			return row
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) findLpTxByID(id uint) *spec.LpTxInfo {
+	for _, row := range m.lpTxs {
+		if row.ID == id && !row.DeletedAt.Valid {
+			return row
+		}
+	}
+	return nil
+}
+
+func matchLpTxFilter(row *spec.LpTxInfo, f *spec.LpTxFilter) bool {
+	if f == nil {
+		return true
+	}
+	if f.BtcAddress != "" && !strings.EqualFold(row.BtcAddress, f.BtcAddress) {
+		return false
+	}
+	if f.EvmAddress != "" && !strings.EqualFold(row.EvmAddress, f.EvmAddress) {
+		return false
+	}
+	if len(f.States) > 0 {
+		found := false
+		for _, s := range f.States {
+			if row.State == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.MinAmount != nil && row.Amount.LessThan(*f.MinAmount) {
+		return false
+	}
+	if f.MaxAmount != nil && row.Amount.GreaterThan(*f.MaxAmount) {
+		return false
+	}
+	if f.CreatedAfter != nil && row.CreatedAt.Before(*f.CreatedAfter) {
+		return false
+	}
+	if f.CreatedBefore != nil && !row.CreatedAt.Before(*f.CreatedBefore) {
+		return false
+	}
+	return true
+}
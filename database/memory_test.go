@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+func TestMemoryStoreLpTransaction(t *testing.T) {
+	var s LpTxStore = NewMemoryStore()
+
+	tx, err := s.CreateAndGetLpTransaction(&spec.LpTxInfo{TxHash: "h1", BtcAddress: "bc1q", EvmAddress: "0xA", Amount: decimal.RequireFromString("1.5")})
+	assert.NoError(t, err)
+	assert.Equal(t, spec.LpTxStateCreated, tx.State)
+
+	err = s.CreateLpTransaction(&spec.LpTxInfo{TxHash: "h1", BtcAddress: "bc1x"})
+	assert.Equal(t, gorm.ErrDuplicatedKey, err)
+
+	got, err := s.GetLpTransaction("h1", "BC1Q")
+	assert.NoError(t, err)
+	assert.Equal(t, tx.ID, got.ID)
+
+	_, err = s.GetLpTransaction("h2", "bc1q")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	assert.Equal(t, ErrLpTxIllegalTransition, s.TransitionLpTransaction("h1", spec.LpTxStateCreated, spec.LpTxStateSuccess))
+	assert.NoError(t, s.TransitionLpTransaction("h1", spec.LpTxStateCreated, spec.LpTxStatePending))
+	assert.Equal(t, ErrLpTxStateConflict, s.TransitionLpTransaction("h1", spec.LpTxStateCreated, spec.LpTxStatePending))
+	assert.Equal(t, gorm.ErrRecordNotFound, s.TransitionLpTransaction("h2", spec.LpTxStateCreated, spec.LpTxStatePending))
+
+	assert.NoError(t, s.DeleteLpTransaction("h1", "bc1q"))
+	_, err = s.GetLpTransaction("h1", "bc1q")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	// the unique index still covers soft deleted rows
+	assert.Equal(t, gorm.ErrDuplicatedKey, s.CreateLpTransaction(&spec.LpTxInfo{TxHash: "h1", BtcAddress: "bc1q"}))
+
+	deleted, _, err := s.ListDeletedLpTransactions(nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+	assert.NoError(t, s.RestoreLpTransaction("h1", "bc1q"))
+	assert.Equal(t, gorm.ErrRecordNotFound, s.RestoreLpTransaction("h1", "bc1q"))
+	_, err = s.GetLpTransaction("h1", "bc1q")
+	assert.NoError(t, err)
+
+	events, err := s.ListLpTransactionEvents("h1")
+	assert.NoError(t, err)
+	assert.Len(t, events, 4)
+	assert.Equal(t, spec.LpTxActionCreate, events[0].Action)
+	assert.Equal(t, spec.LpTxActionTransition, events[1].Action)
+	assert.Equal(t, spec.LpTxActionDelete, events[2].Action)
+	assert.Equal(t, spec.LpTxActionRestore, events[3].Action)
+}
+
+func TestMemoryStoreCreateOrGetLpTransaction(t *testing.T) {
+	s := NewMemoryStore()
+	report := func() *spec.LpTxInfo {
+		return &spec.LpTxInfo{TxHash: "h1", BtcAddress: "bc1q", EvmAddress: "0xA", Amount: decimal.RequireFromString("1.5")}
+	}
+	first, created, err := s.CreateOrGetLpTransaction(report())
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	second, created, err := s.CreateOrGetLpTransaction(report())
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, second.ID)
+
+	other := report()
+	other.Amount = decimal.RequireFromString("2")
+	_, _, err = s.CreateOrGetLpTransaction(other)
+	assert.Equal(t, ErrLpTxPayloadConflict, err)
+
+	events, _ := s.ListLpTransactionEvents("h1")
+	assert.Len(t, events, 1)
+}
+
+func TestMemoryStoreUpdateLpTransaction(t *testing.T) {
+	s := NewMemoryStore()
+	tx, err := s.CreateAndGetLpTransaction(&spec.LpTxInfo{TxHash: "h1", BtcAddress: "bc1q"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), tx.Version)
+
+	first, _ := s.GetLpTransaction("h1", "bc1q")
+	second, _ := s.GetLpTransaction("h1", "bc1q")
+	first.Amount = decimal.NewFromInt(1)
+	assert.NoError(t, s.UpdateLpTransaction(first))
+	assert.Equal(t, uint(2), first.Version)
+
+	second.Amount = decimal.NewFromInt(2)
+	assert.Equal(t, ErrLpTxVersionConflict, s.UpdateLpTransaction(second))
+
+	assert.NoError(t, s.TransitionLpTransaction("h1", spec.LpTxStateCreated, spec.LpTxStatePending))
+	assert.Equal(t, ErrLpTxVersionConflict, s.UpdateLpTransaction(first))
+
+	got, _ := s.GetLpTransaction("h1", "bc1q")
+	assert.Equal(t, uint(3), got.Version)
+	assert.True(t, decimal.NewFromInt(1).Equal(got.Amount))
+}
+
+func TestMemoryStoreListLpTransactions(t *testing.T) {
+	s := NewMemoryStore()
+	for _, h := range []string{"a", "b", "c", "d", "e"} {
+		assert.NoError(t, s.CreateLpTransaction(&spec.LpTxInfo{TxHash: h, BtcAddress: "bc1q"}))
+	}
+
+	var hashes []string
+	page := &spec.Page{Limit: 2}
+	for {
+		txInfos, next, err := s.ListLpTransactions(&spec.LpTxFilter{BtcAddress: "bc1q"}, page)
+		assert.NoError(t, err)
+		for _, tx := range txInfos {
+			hashes = append(hashes, tx.TxHash)
+		}
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	assert.Equal(t, []string{"e", "d", "c", "b", "a"}, hashes)
+
+	_, _, err := s.ListLpTransactions(nil, &spec.Page{Cursor: "bad"})
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestMemoryStoreCanceled(t *testing.T) {
+	s := NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.GetLpTransactionCtx(ctx, "h1", "bc1q")
+	assert.Equal(t, ErrCanceled, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	assert.Equal(t, ErrTimeout, s.CreateLpTransactionCtx(ctx, &spec.LpTxInfo{TxHash: "h1"}))
+}
+
+func TestMemoryStoreWithTx(t *testing.T) {
+	s := NewMemoryStore()
+	s.SetProperty("counter", "0")
+	assert.NoError(t, s.CreateLpTransaction(&spec.LpTxInfo{TxHash: "h1", BtcAddress: "bc1q"}))
+
+	boom := errors.New("boom")
+	err := s.WithTx(context.Background(), func(tx Store) error {
+		assert.NoError(t, tx.TransitionLpTransaction("h1", spec.LpTxStateCreated, spec.LpTxStatePending))
+		assert.NoError(t, tx.UpdateProperty(&spec.Property{Name: "counter", Value: "1"}))
+		return boom
+	})
+	assert.Equal(t, boom, err)
+	tx, _ := s.GetLpTransaction("h1", "bc1q")
+	assert.Equal(t, spec.LpTxStateCreated, tx.State)
+
+	assert.Panics(t, func() {
+		s.WithTx(context.Background(), func(tx Store) error {
+			tx.UpdateProperty(&spec.Property{Name: "counter", Value: "1"})
+			panic(boom)
+		})
+	})
+	v, _ := s.GetProperty("counter")
+	assert.Equal(t, "0", v)
+
+	err = s.WithTx(context.Background(), func(tx Store) error {
+		assert.NoError(t, tx.TransitionLpTransaction("h1", spec.LpTxStateCreated, spec.LpTxStatePending))
+		// a failed nested transaction only reverts its own changes
+		assert.Equal(t, boom, tx.WithTx(context.Background(), func(nested Store) error {
+			assert.NoError(t, nested.UpdateProperty(&spec.Property{Name: "counter", Value: "2"}))
+			return boom
+		}))
+		return tx.UpdateProperty(&spec.Property{Name: "counter", Value: "1"})
+	})
+	assert.NoError(t, err)
+	tx, _ = s.GetLpTransaction("h1", "bc1q")
+	assert.Equal(t, spec.LpTxStatePending, tx.State)
+	v, _ = s.GetProperty("counter")
+	assert.Equal(t, "1", v)
+}
+
+func TestMemoryStoreProperty(t *testing.T) {
+	s := NewMemoryStore()
+	_, err := s.GetProperty("p")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	s.SetProperty("p", "1")
+	prop, err := s.UpdateAndGetProperty(&spec.Property{Name: "p", Value: "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", prop.Value)
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"hash/fnv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	zlog "github.com/fiamma-chain/fiamma-go-sdk/log"
+)
+
+const migrationLockName = "fiamma:schema_migrations"
+
+// Migration is a versioned schema change, Up and Down run in their own transaction
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is the bookkeeping row of an applied migration
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Migrations returns all known migrations ordered by version
+func Migrations() []Migration {
+	return append([]Migration(nil), migrations...)
+}
+
+// Migrate applies all pending migrations.
+// Replicas are serialized by a postgres advisory lock, so only one of them migrates.
+func (db *Database) Migrate() error {
+	return db.withMigrationLock(func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if applied[m.Version] {
+				continue
+			}
+			db.log.Info("to apply migration", zlog.Any("version", m.Version), zlog.Any("name", m.Name))
+			err = conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return errors.Errorf("failed to apply migration %d_%s: %s", m.Version, m.Name, err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateDown reverts the latest applied migrations, at most steps of them
+func (db *Database) MigrateDown(steps int) error {
+	return db.withMigrationLock(func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if !applied[m.Version] {
+				continue
+			}
+			db.log.Info("to revert migration", zlog.Any("version", m.Version), zlog.Any("name", m.Name))
+			err = conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, m.Version).Error
+			})
+			if err != nil {
+				return errors.Errorf("failed to revert migration %d_%s: %s", m.Version, m.Name, err.Error())
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// MigrationVersion returns the latest applied migration version, 0 if none
+func (db *Database) MigrationVersion() (int64, error) {
+	if !db.DB.Migrator().HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+	var version int64
+	err := db.DB.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// withMigrationLock pins a single connection, since advisory locks belong to the session
+func (db *Database) withMigrationLock(fn func(conn *gorm.DB) error) error {
+	return db.DB.Connection(func(conn *gorm.DB) error {
+		key := advisoryLockKey(migrationLockName)
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", key).Error; err != nil {
+			return err
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", key).Error; err != nil {
+				db.log.Warn("failed to release migration lock", zlog.Error(err))
+			}
+		}()
+		if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func appliedMigrations(conn *gorm.DB) (map[int64]bool, error) {
+	var versions []int64
+	if err := conn.Model(&SchemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+// advisoryLockKey hashes a lock name into the int64 key space of postgres advisory locks
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// migrations must be kept ordered by version, never edit a released migration, append a new one
+var migrations = []Migration{
+	sqlMigration(1, "enable_citext",
+		[]string{
+			`CREATE EXTENSION IF NOT EXISTS citext`,
+		},
+		[]string{
+			`DROP EXTENSION IF EXISTS citext`,
+		},
+	),
+	sqlMigration(2, "create_lp_tx_infos",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS lp_tx_infos (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz,
+				updated_at timestamptz,
+				deleted_at timestamptz,
+				tx_hash text NOT NULL,
+				btc_address citext NOT NULL,
+				evm_address citext NOT NULL,
+				amount decimal(24,6) DEFAULT 0,
+				state text NOT NULL DEFAULT 'created'
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_lp_tx_infos_tx_hash ON lp_tx_infos (tx_hash)`,
+			`CREATE INDEX IF NOT EXISTS idx_lp_tx_infos_btc_address ON lp_tx_infos (btc_address)`,
+			`CREATE INDEX IF NOT EXISTS idx_lp_tx_infos_evm_address ON lp_tx_infos (evm_address)`,
+			`CREATE INDEX IF NOT EXISTS idx_lp_tx_infos_deleted_at ON lp_tx_infos (deleted_at)`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS lp_tx_infos`,
+		},
+	),
+	sqlMigration(3, "create_properties",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS properties (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz,
+				updated_at timestamptz,
+				deleted_at timestamptz,
+				name text NOT NULL CONSTRAINT uni_properties_name UNIQUE,
+				value text NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_properties_deleted_at ON properties (deleted_at)`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS properties`,
+		},
+	),
+	sqlMigration(4, "create_lp_tx_events",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS lp_tx_events (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz NOT NULL,
+				tx_hash text NOT NULL,
+				action text NOT NULL,
+				operator text NOT NULL DEFAULT '',
+				from_state text NOT NULL DEFAULT '',
+				to_state text NOT NULL DEFAULT '',
+				before text NOT NULL DEFAULT '',
+				after text NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_lp_tx_events_tx_hash ON lp_tx_events (tx_hash)`,
+			`CREATE INDEX IF NOT EXISTS idx_lp_tx_events_created_at ON lp_tx_events (created_at)`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS lp_tx_events`,
+		},
+	),
+	sqlMigration(5, "notify_property_changes",
+		[]string{
+			`CREATE OR REPLACE FUNCTION notify_property_changed() RETURNS trigger AS $$
+			BEGIN
+				PERFORM pg_notify('` + PropertyNotifyChannel + `', NEW.name);
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql`,
+			`DROP TRIGGER IF EXISTS properties_notify ON properties`,
+			`CREATE TRIGGER properties_notify AFTER INSERT OR UPDATE ON properties
+				FOR EACH ROW EXECUTE FUNCTION notify_property_changed()`,
+		},
+		[]string{
+			`DROP TRIGGER IF EXISTS properties_notify ON properties`,
+			`DROP FUNCTION IF EXISTS notify_property_changed()`,
+		},
+	),
+	sqlMigration(6, "add_lp_tx_infos_version",
+		[]string{
+			`ALTER TABLE lp_tx_infos ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1`,
+		},
+		[]string{
+			`ALTER TABLE lp_tx_infos DROP COLUMN IF EXISTS version`,
+		},
+	),
+	sqlMigration(7, "create_outbox_messages",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS outbox_messages (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz NOT NULL,
+				updated_at timestamptz,
+				topic text NOT NULL,
+				key text NOT NULL DEFAULT '',
+				payload text NOT NULL,
+				state text NOT NULL DEFAULT 'pending',
+				attempts bigint NOT NULL DEFAULT 0,
+				next_attempt_at timestamptz NOT NULL,
+				last_error text NOT NULL DEFAULT '',
+				delivered_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending ON outbox_messages (next_attempt_at, id)
+				WHERE state = 'pending'`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS outbox_messages`,
+		},
+	),
+	sqlMigration(8, "create_jobs",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS jobs (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz NOT NULL,
+				updated_at timestamptz,
+				queue text NOT NULL,
+				payload text NOT NULL DEFAULT '',
+				state text NOT NULL DEFAULT 'pending',
+				attempts bigint NOT NULL DEFAULT 0,
+				max_attempts bigint NOT NULL,
+				run_at timestamptz NOT NULL,
+				locked_until timestamptz,
+				last_error text NOT NULL DEFAULT '',
+				finished_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_jobs_runnable ON jobs (queue, run_at, id)
+				WHERE state IN ('pending', 'running')`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS jobs`,
+		},
+	),
+	sqlMigration(9, "add_outbox_messages_locked_until",
+		[]string{
+			`ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS locked_until timestamptz`,
+		},
+		[]string{
+			`ALTER TABLE outbox_messages DROP COLUMN IF EXISTS locked_until`,
+		},
+	),
+	sqlMigration(10, "create_leader_leases",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS leader_leases (
+				name text PRIMARY KEY,
+				holder text NOT NULL,
+				expire_at timestamptz NOT NULL
+			)`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS leader_leases`,
+		},
+	),
+}
+
+func sqlMigration(version int64, name string, up, down []string) Migration {
+	return Migration{
+		Version: version,
+		Name:    name,
+		Up:      execStatements(up),
+		Down:    execStatements(down),
+	}
+}
+
+func execStatements(stmts []string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,283 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/http"
+	zlog "github.com/fiamma-chain/fiamma-go-sdk/log"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+	"github.com/fiamma-chain/fiamma-go-sdk/utils"
+)
+
+// Publisher delivers outbox messages. A message may be published more than once,
+// so consumers must deduplicate by its id.
+type Publisher interface {
+	Publish(ctx context.Context, msg *spec.OutboxMessage) error
+}
+
+// OutboxRelayConfig outbox relay config
+type OutboxRelayConfig struct {
+	// PollInterval is how often pending messages are polled when the outbox is drained
+	PollInterval time.Duration `yaml:"pollInterval" json:"pollInterval" default:"1s"`
+	// BatchSize is the number of messages claimed and published per round
+	BatchSize int `yaml:"batchSize" json:"batchSize" default:"100"`
+	// LockTimeout bounds the publishing of a claimed batch, after which its unpublished messages
+	// are considered abandoned and claimed again
+	LockTimeout time.Duration `yaml:"lockTimeout" json:"lockTimeout" default:"1m"`
+	// MaxAttempts is the number of attempts before a message is marked as failed, 0 retries forever
+	MaxAttempts int `yaml:"maxAttempts" json:"maxAttempts"`
+	// MinBackoff and MaxBackoff bound the exponential delay between attempts
+	MinBackoff time.Duration `yaml:"minBackoff" json:"minBackoff" default:"1s"`
+	MaxBackoff time.Duration `yaml:"maxBackoff" json:"maxBackoff" default:"5m"`
+}
+
+// outboxQueue claims due messages and records their delivery, implemented by Database
+type outboxQueue interface {
+	claimOutboxMessages(ctx context.Context, limit int, lockTimeout time.Duration, maxAttempts int) ([]spec.OutboxMessage, error)
+	completeOutboxMessage(ctx context.Context, msg *spec.OutboxMessage, values map[string]interface{}) error
+}
+
+// OutboxRelay publishes pending outbox messages. Several relays may run concurrently,
+// each message is claimed by a single relay for LockTimeout while it is published.
+type OutboxRelay struct {
+	queue outboxQueue
+	pub   Publisher
+	cfg   OutboxRelayConfig
+	log   *zlog.Logger
+}
+
+// NewOutboxRelay creates a relay, zero config values are set to their defaults
+func NewOutboxRelay(db *Database, pub Publisher, cfg OutboxRelayConfig) (*OutboxRelay, error) {
+	if db == nil {
+		return nil, errors.New("outbox relay requires a database")
+	}
+	return newOutboxRelay(db, pub, cfg)
+}
+
+func newOutboxRelay(queue outboxQueue, pub Publisher, cfg OutboxRelayConfig) (*OutboxRelay, error) {
+	if pub == nil {
+		return nil, errors.New("outbox relay requires a publisher")
+	}
+	if err := utils.SetDefaults(&cfg); err != nil {
+		return nil, err
+	}
+	return &OutboxRelay{
+		queue: queue,
+		pub:   pub,
+		cfg:   cfg,
+		log:   zlog.L().With(zlog.Any("service", "outbox")),
+	}, nil
+}
+
+// Run relays messages until ctx is done
+func (r *OutboxRelay) Run(ctx context.Context) {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.Error("failed to relay outbox messages", zlog.Error(err))
+		}
+		if n == r.cfg.BatchSize && err == nil {
+			// there may be more pending messages
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+// RelayOnce publishes one batch of due messages and returns the number of attempted messages.
+// The batch is claimed in a short statement, then every message is published outside of any
+// transaction and marked on its own. A message is marked as delivered only after the publisher
+// succeeded, so a crash in between leads to a redelivery once its claim expired instead of a lost message.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	msgs, err := r.queue.claimOutboxMessages(ctx, r.cfg.BatchSize, r.cfg.LockTimeout, r.cfg.MaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+	// never publish past the claim, another relay may have claimed the message again
+	pubCtx, cancel := context.WithTimeout(ctx, r.cfg.LockTimeout)
+	defer cancel()
+	var n int
+	var firstErr error
+	for i := range msgs {
+		if pubCtx.Err() != nil {
+			// the remaining messages are claimed again once their claim expired
+			break
+		}
+		values := r.deliver(pubCtx, &msgs[i])
+		// record the result even if the relay is stopping, so the message is not published again
+		if err = r.queue.completeOutboxMessage(context.Background(), &msgs[i], values); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		n++
+	}
+	return n, firstErr
+}
+
+// deliver publishes the message and returns the columns to update
+func (r *OutboxRelay) deliver(ctx context.Context, msg *spec.OutboxMessage) map[string]interface{} {
+	err := r.pub.Publish(ctx, msg)
+	if err == nil {
+		return map[string]interface{}{
+			"state":        spec.OutboxStateDelivered,
+			"locked_until": nil,
+			"last_error":   "",
+			"delivered_at": time.Now(),
+		}
+	}
+	r.log.Warn("failed to publish outbox message",
+		zlog.Any("id", msg.ID), zlog.Any("topic", msg.Topic), zlog.Any("attempts", msg.Attempts), zlog.Error(err))
+	state := spec.OutboxStatePending
+	if r.cfg.MaxAttempts > 0 && msg.Attempts >= r.cfg.MaxAttempts {
+		state = spec.OutboxStateFailed
+	}
+	return map[string]interface{}{
+		"state":           state,
+		"locked_until":    nil,
+		"last_error":      err.Error(),
+		"next_attempt_at": time.Now().Add(r.backoff(msg.Attempts)),
+	}
+}
+
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	return expBackoff(r.cfg.MinBackoff, r.cfg.MaxBackoff, attempts)
+}
+
+// expBackoff doubles min for every attempt after the first one, up to max
+func expBackoff(min, max time.Duration, attempts int) time.Duration {
+	d := min
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// claimOutboxMessages claims due messages for lockTimeout and counts their attempt.
+// Abandoned messages which exhausted their attempts are marked as failed instead of being claimed again.
+func (db *Database) claimOutboxMessages(ctx context.Context, limit int, lockTimeout time.Duration, maxAttempts int) ([]spec.OutboxMessage, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	now := time.Now()
+	if maxAttempts > 0 {
+		err := db.DB.WithContext(ctx).Model(&spec.OutboxMessage{}).
+			Where("state = ? AND locked_until < ? AND attempts >= ?", spec.OutboxStatePending, now, maxAttempts).
+			Updates(map[string]interface{}{
+				"state":        spec.OutboxStateFailed,
+				"locked_until": nil,
+				"last_error":   "lease expired",
+			}).Error
+		if err != nil {
+			return nil, ctxError(ctx, err)
+		}
+	}
+	var msgs []spec.OutboxMessage
+	err := db.DB.WithContext(ctx).Raw(`UPDATE outbox_messages SET attempts = attempts + 1, locked_until = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE state = ? AND next_attempt_at <= ? AND (locked_until IS NULL OR locked_until < ?)
+				AND (? = 0 OR attempts < ?)
+			ORDER BY next_attempt_at, id LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		) RETURNING *`,
+		now.Add(lockTimeout), now,
+		spec.OutboxStatePending, now, now, maxAttempts, maxAttempts, limit,
+	).Scan(&msgs).Error
+	if err != nil {
+		return nil, ctxError(ctx, err)
+	}
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(msgs, func(i, j int) bool {
+		if !msgs[i].NextAttemptAt.Equal(msgs[j].NextAttemptAt) {
+			return msgs[i].NextAttemptAt.Before(msgs[j].NextAttemptAt)
+		}
+		return msgs[i].ID < msgs[j].ID
+	})
+	return msgs, nil
+}
+
+// completeOutboxMessage records the delivery, unless the message has been claimed again after its claim expired
+func (db *Database) completeOutboxMessage(ctx context.Context, msg *spec.OutboxMessage, values map[string]interface{}) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	res := db.DB.WithContext(ctx).Model(&spec.OutboxMessage{}).
+		Where("id = ? AND state = ? AND attempts = ?", msg.ID, spec.OutboxStatePending, msg.Attempts).
+		Updates(values)
+	if res.Error != nil {
+		return ctxError(ctx, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errors.Errorf("outbox message %d has been claimed by another relay", msg.ID)
+	}
+	return nil
+}
+
+// RetryFailedOutboxMessages makes failed messages pending again and returns their number
+func (db *Database) RetryFailedOutboxMessages() (int64, error) {
+	return db.RetryFailedOutboxMessagesCtx(db.ctx())
+}
+
+func (db *Database) RetryFailedOutboxMessagesCtx(ctx context.Context) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	res := db.DB.WithContext(ctx).Model(&spec.OutboxMessage{}).Where("state = ?", spec.OutboxStateFailed).
+		Updates(map[string]interface{}{
+			"state":           spec.OutboxStatePending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	return res.RowsAffected, ctxError(ctx, res.Error)
+}
+
+func enqueueOutboxMessage(tx *gorm.DB, msg *spec.OutboxMessage) error {
+	msg.State = spec.OutboxStatePending
+	if msg.NextAttemptAt.IsZero() {
+		msg.NextAttemptAt = time.Now()
+	}
+	return tx.Create(msg).Error
+}
+
+// WebhookPublisher posts every message as json to a webhook.
+// The message id is sent in the OutboxIDHeader header for deduplication.
+type WebhookPublisher struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+// OutboxIDHeader is the header carrying the outbox message id
+const OutboxIDHeader = "X-Outbox-Id"
+
+// NewWebhookPublisher creates a webhook publisher, the url may be relative to the client address
+func NewWebhookPublisher(client *http.Client, url string, headers map[string]string) *WebhookPublisher {
+	return &WebhookPublisher{
+		client:  client,
+		url:     url,
+		headers: headers,
+	}
+}
+
+// Publish posts the message, any response outside of 2xx is an error
+func (p *WebhookPublisher) Publish(ctx context.Context, msg *spec.OutboxMessage) error {
+	body, err := json.Marshal(struct {
+		*spec.OutboxMessage
+		Payload json.RawMessage `json:"payload"`
+	}{msg, json.RawMessage(msg.Payload)})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = p.client.PostJSONCtx(ctx, p.url, body, p.headers, map[string]string{OutboxIDHeader: strconv.FormatUint(uint64(msg.ID), 10)})
+	return err
+}
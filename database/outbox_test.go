@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+func TestOutboxRelayBackoff(t *testing.T) {
+	r, err := newOutboxRelay(newMemoryOutbox(0), &fakePublisher{}, OutboxRelayConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, r.backoff(1))
+	assert.Equal(t, 2*time.Second, r.backoff(2))
+	assert.Equal(t, 8*time.Second, r.backoff(4))
+	assert.Equal(t, 5*time.Minute, r.backoff(30))
+}
+
+func TestNewOutboxRelay(t *testing.T) {
+	_, err := NewOutboxRelay(nil, &fakePublisher{}, OutboxRelayConfig{})
+	assert.Error(t, err)
+	_, err = NewOutboxRelay(&Database{}, nil, OutboxRelayConfig{})
+	assert.Error(t, err)
+	_, err = NewOutboxRelay(&Database{}, &fakePublisher{}, OutboxRelayConfig{})
+	assert.NoError(t, err)
+}
+
+// memoryOutbox is an outboxQueue keeping the messages in memory
+type memoryOutbox struct {
+	mu   sync.Mutex
+	msgs []*spec.OutboxMessage
+	// claimed tracks the messages claimed but not completed yet
+	claimed map[uint]bool
+}
+
+func (q *memoryOutbox) claimOutboxMessages(_ context.Context, limit int, lockTimeout time.Duration, maxAttempts int) ([]spec.OutboxMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	var msgs []spec.OutboxMessage
+	for _, m := range q.msgs {
+		if len(msgs) >= limit {
+			break
+		}
+		if m.State != spec.OutboxStatePending || m.NextAttemptAt.After(now) ||
+			(m.LockedUntil != nil && !m.LockedUntil.Before(now)) || (maxAttempts > 0 && m.Attempts >= maxAttempts) {
+			continue
+		}
+		until := now.Add(lockTimeout)
+		m.Attempts++
+		m.LockedUntil = &until
+		q.claimed[m.ID] = true
+		msgs = append(msgs, *m)
+	}
+	return msgs, nil
+}
+
+func (q *memoryOutbox) completeOutboxMessage(_ context.Context, msg *spec.OutboxMessage, values map[string]interface{}) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, m := range q.msgs {
+		if m.ID != msg.ID || m.Attempts != msg.Attempts {
+			continue
+		}
+		m.State = values["state"].(string)
+		m.LastError = values["last_error"].(string)
+		m.LockedUntil = nil
+		if at, ok := values["next_attempt_at"].(time.Time); ok {
+			m.NextAttemptAt = at
+		}
+		delete(q.claimed, m.ID)
+		return nil
+	}
+	return errors.Errorf("outbox message %d has been claimed by another relay", msg.ID)
+}
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []uint
+	fail      map[uint]bool
+	block     bool
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, msg *spec.OutboxMessage) error {
+	if p.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail[msg.ID] {
+		return errors.New("unavailable")
+	}
+	p.published = append(p.published, msg.ID)
+	return nil
+}
+
+func newMemoryOutbox(n int) *memoryOutbox {
+	q := &memoryOutbox{claimed: map[uint]bool{}}
+	for i := 1; i <= n; i++ {
+		q.msgs = append(q.msgs, &spec.OutboxMessage{ID: uint(i), State: spec.OutboxStatePending, NextAttemptAt: time.Now().Add(-time.Second)})
+	}
+	return q
+}
+
+func TestOutboxRelayOnce(t *testing.T) {
+	q := newMemoryOutbox(3)
+	pub := &fakePublisher{fail: map[uint]bool{2: true}}
+	r, err := newOutboxRelay(q, pub, OutboxRelayConfig{MaxAttempts: 2})
+	assert.NoError(t, err)
+
+	n, err := r.RelayOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []uint{1, 3}, pub.published)
+	assert.Empty(t, q.claimed)
+	assert.Equal(t, spec.OutboxStateDelivered, q.msgs[0].State)
+	assert.Equal(t, spec.OutboxStatePending, q.msgs[1].State)
+	assert.Equal(t, "unavailable", q.msgs[1].LastError)
+	assert.True(t, q.msgs[1].NextAttemptAt.After(time.Now()))
+
+	// the failed message is not due before its backoff
+	n, err = r.RelayOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	q.msgs[1].NextAttemptAt = time.Now().Add(-time.Second)
+	n, err = r.RelayOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, spec.OutboxStateFailed, q.msgs[1].State)
+	assert.Equal(t, 2, q.msgs[1].Attempts)
+}
+
+func TestOutboxRelayLockTimeout(t *testing.T) {
+	q := newMemoryOutbox(2)
+	r, err := newOutboxRelay(q, &fakePublisher{block: true}, OutboxRelayConfig{LockTimeout: 50 * time.Millisecond})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	n, err := r.RelayOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	// the first message timed out and is retried, the second one is left to its claim expiry
+	assert.Equal(t, 1, n)
+	assert.Equal(t, spec.OutboxStatePending, q.msgs[0].State)
+	assert.Nil(t, q.msgs[0].LockedUntil)
+	assert.NotNil(t, q.msgs[1].LockedUntil)
+	assert.True(t, q.claimed[2])
+}
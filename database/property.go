@@ -1,48 +1,84 @@
 package database
 
 import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
 	"github.com/fiamma-chain/fiamma-go-sdk/spec"
 )
 
+// GetProperty reads from a replica if any, use Primary to read your own writes
 func (db *Database) GetProperty(name string) (string, error) {
+	return db.GetPropertyCtx(db.ctx(), name)
+}
+
+func (db *Database) GetPropertyCtx(ctx context.Context, name string) (string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 	var prop spec.Property
-	if err := db.DB.Where("name = ?", name).First(&prop).Error; err != nil {
-		return "", err
+	if err := db.reader().WithContext(ctx).Where("name = ?", name).First(&prop).Error; err != nil {
+		return "", ctxError(ctx, err)
 	}
 	return prop.Value, nil
 }
 
+// UpdateProperty updates the value of an existing property, gorm.ErrRecordNotFound is returned if it does not exist
 func (db *Database) UpdateProperty(prop *spec.Property) error {
-	return db.DB.Model(&spec.Property{}).Where("name = ?", prop.Name).
-		Update("value", prop.Value).Error
+	return db.UpdatePropertyCtx(db.ctx(), prop)
 }
 
-func (db *Database) UpdateAndGetProperty(prop *spec.Property) (*spec.Property, error) {
-	tx := db.DB.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
+func (db *Database) UpdatePropertyCtx(ctx context.Context, prop *spec.Property) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	res := db.DB.WithContext(ctx).Model(&spec.Property{}).Where("name = ?", prop.Name).
+		Update("value", prop.Value)
+	if res.Error != nil {
+		return ctxError(ctx, res.Error)
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	if err := tx.Model(&spec.Property{}).Where("name = ?", prop.Name).
-		Update("value", prop.Value).Error; err != nil {
-		tx.Rollback()
-		return nil, err
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
+	return nil
+}
 
-	var newProp spec.Property
-	if err := tx.Where("name = ?", prop.Name).First(&newProp).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
+// UpsertProperty creates the property or updates its value if it already exists
+func (db *Database) UpsertProperty(prop *spec.Property) error {
+	return db.UpsertPropertyCtx(db.ctx(), prop)
+}
 
-	if err := tx.Commit().Error; err != nil {
-		return nil, err
-	}
+func (db *Database) UpsertPropertyCtx(ctx context.Context, prop *spec.Property) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "name"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"value":      prop.Value,
+			"updated_at": time.Now(),
+			"deleted_at": nil,
+		}),
+	}).Create(prop).Error
+	return ctxError(ctx, err)
+}
 
+func (db *Database) UpdateAndGetProperty(prop *spec.Property) (*spec.Property, error) {
+	return db.UpdateAndGetPropertyCtx(db.ctx(), prop)
+}
+
+func (db *Database) UpdateAndGetPropertyCtx(ctx context.Context, prop *spec.Property) (*spec.Property, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var newProp spec.Property
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := db.withDB(tx).UpdatePropertyCtx(ctx, prop); err != nil {
+			return err
+		}
+		return tx.Where("name = ?", prop.Name).First(&newProp).Error
+	})
+	if err != nil {
+		return nil, ctxError(ctx, err)
+	}
 	return &newProp, nil
 }
@@ -0,0 +1,314 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	zlog "github.com/fiamma-chain/fiamma-go-sdk/log"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// PropertiesConfig properties config
+type PropertiesConfig struct {
+	// CacheTTL is how long a value is served from the process-local cache, 0 disables caching
+	CacheTTL time.Duration `yaml:"cacheTTL" json:"cacheTTL" default:"30s"`
+	// PollInterval is how often watched properties are polled, 0 disables polling
+	PollInterval time.Duration `yaml:"pollInterval" json:"pollInterval" default:"10s"`
+}
+
+// propertyListener is implemented by stores notifying property changes, like Database
+type propertyListener interface {
+	ListenPropertyChanges(ctx context.Context, fn func(name string)) error
+}
+
+// propertyListenRetryInterval is the delay before listening again once the notifications are interrupted
+const propertyListenRetryInterval = 5 * time.Second
+
+// Properties provides typed and cached access to a PropertyStore, and notifies watchers of changes
+type Properties struct {
+	store PropertyStore
+	// primary serves the refreshes, which must not read stale values from a replica
+	primary  PropertyStore
+	cfg      PropertiesConfig
+	mu       sync.Mutex
+	cache    map[string]*cachedProperty
+	watchers map[string][]chan string
+	log      *zlog.Logger
+}
+
+type cachedProperty struct {
+	value  string
+	found  bool
+	expire time.Time
+}
+
+// NewProperties creates properties on top of the store
+func NewProperties(store PropertyStore, cfg PropertiesConfig) *Properties {
+	primary := store
+	if db, ok := store.(*Database); ok {
+		primary = db.Primary()
+	}
+	return &Properties{
+		store:    store,
+		primary:  primary,
+		cfg:      cfg,
+		cache:    map[string]*cachedProperty{},
+		watchers: map[string][]chan string{},
+		log:      zlog.L().With(zlog.Any("service", "properties")),
+	}
+}
+
+// Get returns the raw value, gorm.ErrRecordNotFound is returned if the property does not exist
+func (p *Properties) Get(name string) (string, error) {
+	value, found, err := p.load(name)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", gorm.ErrRecordNotFound
+	}
+	return value, nil
+}
+
+// GetString returns the value or def if the property does not exist
+func (p *Properties) GetString(name, def string) (string, error) {
+	value, found, err := p.load(name)
+	if err != nil || !found {
+		return def, err
+	}
+	return value, nil
+}
+
+// GetInt returns the value as int or def if the property does not exist
+func (p *Properties) GetInt(name string, def int) (int, error) {
+	value, found, err := p.load(name)
+	if err != nil || !found {
+		return def, err
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return def, errors.Errorf("failed to parse property %s: %s", name, err.Error())
+	}
+	return v, nil
+}
+
+// GetBool returns the value as bool or def if the property does not exist
+func (p *Properties) GetBool(name string, def bool) (bool, error) {
+	value, found, err := p.load(name)
+	if err != nil || !found {
+		return def, err
+	}
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return def, errors.Errorf("failed to parse property %s: %s", name, err.Error())
+	}
+	return v, nil
+}
+
+// GetDecimal returns the value as decimal or def if the property does not exist
+func (p *Properties) GetDecimal(name string, def decimal.Decimal) (decimal.Decimal, error) {
+	value, found, err := p.load(name)
+	if err != nil || !found {
+		return def, err
+	}
+	v, err := decimal.NewFromString(value)
+	if err != nil {
+		return def, errors.Errorf("failed to parse property %s: %s", name, err.Error())
+	}
+	return v, nil
+}
+
+// GetDuration returns the value as duration, like 1m30s, or def if the property does not exist
+func (p *Properties) GetDuration(name string, def time.Duration) (time.Duration, error) {
+	value, found, err := p.load(name)
+	if err != nil || !found {
+		return def, err
+	}
+	v, err := time.ParseDuration(value)
+	if err != nil {
+		return def, errors.Errorf("failed to parse property %s: %s", name, err.Error())
+	}
+	return v, nil
+}
+
+// GetJSON unmarshals the value into out, out is left untouched if the property does not exist
+func (p *Properties) GetJSON(name string, out interface{}) error {
+	value, found, err := p.load(name)
+	if err != nil || !found {
+		return err
+	}
+	if err = json.Unmarshal([]byte(value), out); err != nil {
+		return errors.Errorf("failed to parse property %s: %s", name, err.Error())
+	}
+	return nil
+}
+
+// Set updates an existing property, gorm.ErrRecordNotFound is returned if it does not exist
+func (p *Properties) Set(name, value string) error {
+	if err := p.store.UpdateProperty(&spec.Property{Name: name, Value: value}); err != nil {
+		return err
+	}
+	p.update(name, value, true)
+	return nil
+}
+
+// Upsert creates the property or updates it if it already exists
+func (p *Properties) Upsert(name, value string) error {
+	if err := p.store.UpsertProperty(&spec.Property{Name: name, Value: value}); err != nil {
+		return err
+	}
+	p.update(name, value, true)
+	return nil
+}
+
+// UpsertJSON marshals the value and upserts it
+func (p *Properties) UpsertJSON(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return p.Upsert(name, string(data))
+}
+
+// Watch returns a channel receiving the new value every time the property changes.
+// Only the latest value is kept if the receiver is slow. Call the returned func to stop watching,
+// which closes the channel.
+func (p *Properties) Watch(name string) (<-chan string, func()) {
+	// remember the current value, so that only later changes are sent
+	if _, _, err := p.load(name); err != nil {
+		p.log.Warn("failed to load watched property", zlog.Any("name", name), zlog.Error(err))
+	}
+	ch := make(chan string, 1)
+	p.mu.Lock()
+	p.watchers[name] = append(p.watchers[name], ch)
+	p.mu.Unlock()
+	return ch, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		chs := p.watchers[name]
+		for i, c := range chs {
+			if c == ch {
+				p.watchers[name] = append(chs[:i], chs[i+1:]...)
+				// closed while p.mu is held, so update never sends on it
+				close(ch)
+				break
+			}
+		}
+		if len(p.watchers[name]) == 0 {
+			delete(p.watchers, name)
+		}
+	}
+}
+
+// Refresh reloads the property from the primary and notifies watchers if it changed.
+// It is called by the poller and on change notifications.
+func (p *Properties) Refresh(name string) {
+	value, err := p.primary.GetProperty(name)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		p.log.Warn("failed to refresh property", zlog.Any("name", name), zlog.Error(err))
+		return
+	}
+	p.update(name, value, err == nil)
+}
+
+// Start polls watched properties every PollInterval until ctx is done. If the store notifies changes,
+// like Database does, changed properties are also refreshed as soon as they are notified.
+func (p *Properties) Start(ctx context.Context) {
+	if l, ok := p.store.(propertyListener); ok {
+		go p.listen(ctx, l)
+	}
+	if p.cfg.PollInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(p.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				names := make([]string, 0, len(p.watchers))
+				for name := range p.watchers {
+					names = append(names, name)
+				}
+				p.mu.Unlock()
+				for _, name := range names {
+					p.Refresh(name)
+				}
+			}
+		}
+	}()
+}
+
+// listen refreshes the notified properties until ctx is done, and listens again if the notifications are interrupted
+func (p *Properties) listen(ctx context.Context, l propertyListener) {
+	for {
+		err := l.ListenPropertyChanges(ctx, p.notified)
+		if ctx.Err() != nil {
+			return
+		}
+		p.log.Warn("property change notifications are interrupted", zlog.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(propertyListenRetryInterval):
+		}
+	}
+}
+
+// notified refreshes a changed property if it is cached or watched
+func (p *Properties) notified(name string) {
+	p.mu.Lock()
+	_, cached := p.cache[name]
+	_, watched := p.watchers[name]
+	p.mu.Unlock()
+	if cached || watched {
+		p.Refresh(name)
+	}
+}
+
+func (p *Properties) load(name string) (string, bool, error) {
+	p.mu.Lock()
+	c, ok := p.cache[name]
+	p.mu.Unlock()
+	if ok && time.Now().Before(c.expire) {
+		return c.value, c.found, nil
+	}
+
+	value, err := p.store.GetProperty(name)
+	found := true
+	if err == gorm.ErrRecordNotFound {
+		found = false
+	} else if err != nil {
+		return "", false, err
+	}
+	p.update(name, value, found)
+	return value, found, nil
+}
+
+// update caches the value and notifies watchers if it differs from the cached one
+func (p *Properties) update(name, value string, found bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old, ok := p.cache[name]
+	p.cache[name] = &cachedProperty{value: value, found: found, expire: time.Now().Add(p.cfg.CacheTTL)}
+	if !found || (ok && old.found && old.value == value) {
+		return
+	}
+	for _, ch := range p.watchers[name] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- value
+	}
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+func TestProperties(t *testing.T) {
+	store := NewMemoryStore()
+	props := NewProperties(store, PropertiesConfig{CacheTTL: time.Minute})
+
+	n, err := props.GetInt("limit", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, gorm.ErrRecordNotFound, props.Set("limit", "20"))
+
+	assert.NoError(t, props.Upsert("limit", "20"))
+	n, err = props.GetInt("limit", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, n)
+
+	ch, stop := props.Watch("limit")
+	defer stop()
+
+	// changes made behind the cache are only seen after a refresh
+	assert.NoError(t, store.UpdateProperty(&spec.Property{Name: "limit", Value: "30"}))
+	n, _ = props.GetInt("limit", 10)
+	assert.Equal(t, 20, n)
+	props.Refresh("limit")
+	assert.Equal(t, "30", <-ch)
+	n, _ = props.GetInt("limit", 10)
+	assert.Equal(t, 30, n)
+
+	// unchanged values are not sent again
+	props.Refresh("limit")
+	select {
+	case v := <-ch:
+		t.Fatalf("unexpected change %s", v)
+	default:
+	}
+
+	assert.NoError(t, props.Upsert("enabled", "yes"))
+	_, err = props.GetBool("enabled", false)
+	assert.Error(t, err)
+}
+
+func TestPropertiesWatchStop(t *testing.T) {
+	props := NewProperties(NewMemoryStore(), PropertiesConfig{})
+	ch, stop := props.Watch("limit")
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	stop()
+	stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watch channel is not closed")
+	}
+	// changes are not sent to stopped watchers
+	assert.NoError(t, props.Upsert("limit", "1"))
+}
+
+func TestPropertiesRefreshFromPrimary(t *testing.T) {
+	db := &Database{DB: &gorm.DB{}, replicas: []*gorm.DB{{}}, next: new(uint64)}
+	props := NewProperties(db, PropertiesConfig{})
+	assert.Same(t, db, props.store)
+	assert.Empty(t, props.primary.(*Database).replicas)
+}
+
+// notifyingStore is a memory store notifying the changes sent to its channel
+type notifyingStore struct {
+	*MemoryStore
+	changes chan string
+}
+
+func (s *notifyingStore) ListenPropertyChanges(ctx context.Context, fn func(name string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case name := <-s.changes:
+			fn(name)
+		}
+	}
+}
+
+func TestPropertiesNotifications(t *testing.T) {
+	store := &notifyingStore{MemoryStore: NewMemoryStore(), changes: make(chan string)}
+	assert.NoError(t, store.UpsertProperty(&spec.Property{Name: "limit", Value: "1"}))
+	// no polling, changes are only seen through the notifications
+	props := NewProperties(store, PropertiesConfig{CacheTTL: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	props.Start(ctx)
+
+	ch, stop := props.Watch("limit")
+	defer stop()
+	assert.NoError(t, store.UpdateProperty(&spec.Property{Name: "limit", Value: "2"}))
+	store.changes <- "other"
+	store.changes <- "limit"
+	assert.Equal(t, "2", <-ch)
+	n, err := props.GetInt("limit", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
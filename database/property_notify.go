@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+)
+
+// PropertyNotifyChannel is the postgres channel notified with the property name on every insert or update
+const PropertyNotifyChannel = "fiamma_property"
+
+// ListenPropertyChanges blocks and calls fn with the name of every changed property until ctx is done.
+// It holds a dedicated connection and relies on the trigger created by the migrations.
+func (db *Database) ListenPropertyChanges(ctx context.Context, fn func(name string)) error {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn interface{}) error {
+		sc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("failed to listen, the driver is not pgx")
+		}
+		pc := sc.Conn()
+		if _, err := pc.Exec(ctx, "LISTEN "+PropertyNotifyChannel); err != nil {
+			return err
+		}
+		defer func() {
+			if !pc.IsClosed() {
+				pc.Exec(context.Background(), "UNLISTEN "+PropertyNotifyChannel) //nolint:errcheck
+			}
+		}()
+		for {
+			n, err := pc.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			fn(n.Payload)
+		}
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.Trace(err)
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// LpTxStore stores lp transactions and their audit events
+type LpTxStore interface {
+	GetLpTransaction(txHash, btcAddress string) (*spec.LpTxInfo, error)
+	CreateLpTransaction(tx *spec.LpTxInfo) error
+	CreateAndGetLpTransaction(tx *spec.LpTxInfo) (*spec.LpTxInfo, error)
+	CreateOrGetLpTransaction(tx *spec.LpTxInfo) (*spec.LpTxInfo, bool, error)
+	ListLpTransactionByAddress(btcAddress string) (*[]spec.LpTxInfo, error)
+	ListLpTransactions(filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error)
+	UpdateLpTransaction(tx *spec.LpTxInfo) error
+	TransitionLpTransaction(txHash, from, to string) error
+	DeleteLpTransaction(txHash, btcAddress string) error
+	ListDeletedLpTransactions(filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error)
+	RestoreLpTransaction(txHash, btcAddress string) error
+	ListLpTransactionEvents(txHash string) ([]spec.LpTxEvent, error)
+
+	GetLpTransactionCtx(ctx context.Context, txHash, btcAddress string) (*spec.LpTxInfo, error)
+	CreateLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) error
+	CreateAndGetLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) (*spec.LpTxInfo, error)
+	CreateOrGetLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) (*spec.LpTxInfo, bool, error)
+	ListLpTransactionByAddressCtx(ctx context.Context, btcAddress string) (*[]spec.LpTxInfo, error)
+	ListLpTransactionsCtx(ctx context.Context, filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error)
+	UpdateLpTransactionCtx(ctx context.Context, tx *spec.LpTxInfo) error
+	TransitionLpTransactionCtx(ctx context.Context, txHash, from, to string) error
+	DeleteLpTransactionCtx(ctx context.Context, txHash, btcAddress string) error
+	ListDeletedLpTransactionsCtx(ctx context.Context, filter *spec.LpTxFilter, page *spec.Page) ([]spec.LpTxInfo, string, error)
+	RestoreLpTransactionCtx(ctx context.Context, txHash, btcAddress string) error
+	ListLpTransactionEventsCtx(ctx context.Context, txHash string) ([]spec.LpTxEvent, error)
+}
+
+// PropertyStore stores properties
+type PropertyStore interface {
+	GetProperty(name string) (string, error)
+	UpdateProperty(prop *spec.Property) error
+	UpsertProperty(prop *spec.Property) error
+	UpdateAndGetProperty(prop *spec.Property) (*spec.Property, error)
+
+	GetPropertyCtx(ctx context.Context, name string) (string, error)
+	UpdatePropertyCtx(ctx context.Context, prop *spec.Property) error
+	UpsertPropertyCtx(ctx context.Context, prop *spec.Property) error
+	UpdateAndGetPropertyCtx(ctx context.Context, prop *spec.Property) (*spec.Property, error)
+}
+
+// Store provides all lp transaction and property methods, it is also the view passed to WithTx
+type Store interface {
+	LpTxStore
+	PropertyStore
+	// WithTx runs fn in a transaction, or in a savepoint if the store is already transactional
+	WithTx(ctx context.Context, fn func(tx Store) error) error
+}
+
+var (
+	_ Store         = (*Database)(nil)
+	_ Store         = (*MemoryStore)(nil)
+	_ LpTxStore     = (*Database)(nil)
+	_ PropertyStore = (*Database)(nil)
+	_ LpTxStore     = (*MemoryStore)(nil)
+	_ PropertyStore = (*MemoryStore)(nil)
+)
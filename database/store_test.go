@@ -0,0 +1,66 @@
+package database
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// testStores returns the store implementations which must behave the same way,
+// the database one is skipped if there is no test database
+func testStores() map[string]func(t *testing.T) Store {
+	return map[string]func(t *testing.T) Store{
+		"memory":   func(*testing.T) Store { return NewMemoryStore() },
+		"database": func(t *testing.T) Store { return testDB(t) },
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	for name, open := range testStores() {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			// the test database is shared, never reuse a tx hash
+			suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+			h1, h2 := "h1-"+suffix, "h2-"+suffix
+
+			tx := &spec.LpTxInfo{TxHash: h1, BtcAddress: "bc1q", EvmAddress: "0xA", Amount: decimal.RequireFromString("1.5")}
+			assert.NoError(t, s.CreateLpTransaction(tx))
+			assert.Equal(t, gorm.ErrDuplicatedKey, s.CreateLpTransaction(&spec.LpTxInfo{TxHash: h1, BtcAddress: "bc1x"}))
+
+			_, err := s.GetLpTransaction(h2, "bc1q")
+			assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+			got, created, err := s.CreateOrGetLpTransaction(&spec.LpTxInfo{TxHash: h1, BtcAddress: "BC1Q", EvmAddress: "0xa", Amount: decimal.RequireFromString("1.500")})
+			assert.NoError(t, err)
+			assert.False(t, created)
+			assert.Equal(t, tx.ID, got.ID)
+			_, _, err = s.CreateOrGetLpTransaction(&spec.LpTxInfo{TxHash: h1, BtcAddress: "bc1q", EvmAddress: "0xA", Amount: decimal.RequireFromString("2")})
+			assert.Equal(t, ErrLpTxPayloadConflict, err)
+
+			other := &spec.LpTxInfo{TxHash: h2, BtcAddress: "bc1q"}
+			assert.NoError(t, s.CreateLpTransaction(other))
+			other.TxHash = h1
+			assert.Equal(t, gorm.ErrDuplicatedKey, s.UpdateLpTransaction(other))
+			missing := &spec.LpTxInfo{TxHash: "h3-" + suffix}
+			missing.ID = other.ID + 1000000
+			assert.Equal(t, gorm.ErrRecordNotFound, s.UpdateLpTransaction(missing))
+			// an update never inserts
+			assert.Equal(t, gorm.ErrRecordNotFound, s.UpdateLpTransaction(&spec.LpTxInfo{TxHash: "h3-" + suffix}))
+			_, err = s.GetLpTransaction("h3-"+suffix, "")
+			assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+			assert.Equal(t, gorm.ErrRecordNotFound, s.TransitionLpTransaction("h3-"+suffix, spec.LpTxStateCreated, spec.LpTxStatePending))
+			assert.Equal(t, ErrLpTxStateConflict, s.TransitionLpTransaction(h1, spec.LpTxStatePending, spec.LpTxStateProcessing))
+
+			_, err = s.GetProperty("missing-" + suffix)
+			assert.Equal(t, gorm.ErrRecordNotFound, err)
+			assert.Equal(t, gorm.ErrRecordNotFound, s.UpdateProperty(&spec.Property{Name: "missing-" + suffix, Value: "1"}))
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// WithTx runs fn in a transaction, tx is a transaction-scoped view of all lp transaction and property methods.
+// The transaction is rolled back if fn returns an error or panics, the panic is re-raised after the rollback.
+// Calling WithTx on tx creates a nested transaction backed by a savepoint.
+func (db *Database) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(db.withDB(tx))
+	})
+	return ctxError(ctx, err)
+}
+
+// withDB returns a view of the database bound to the session, reads are never routed to replicas
+func (db *Database) withDB(session *gorm.DB) *Database {
+	n := *db
+	n.DB = session
+	n.replicas = nil
+	return &n
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+func TestWithTxSavepoint(t *testing.T) {
+	ctx := context.Background()
+	db, f := newFakeDatabase(t, func(query string, _ []interface{}) fakeResult {
+		switch {
+		case strings.HasPrefix(query, `UPDATE "lp_tx_infos"`):
+			return fakeResult{affected: 1}
+		case strings.HasPrefix(query, `INSERT INTO "lp_tx_events"`):
+			return insertedID(1)
+		}
+		return fakeResult{}
+	})
+	failed := errors.New("failed")
+	err := db.WithTx(ctx, func(tx Store) error {
+		// the failed nested transaction only rolls back its savepoint
+		err := tx.WithTx(ctx, func(nested Store) error {
+			if err := nested.TransitionLpTransactionCtx(ctx, "h1", spec.LpTxStateCreated, spec.LpTxStatePending); err != nil {
+				return err
+			}
+			return failed
+		})
+		assert.Equal(t, failed, err)
+		return tx.TransitionLpTransactionCtx(ctx, "h2", spec.LpTxStateCreated, spec.LpTxStatePending)
+	})
+	assert.NoError(t, err)
+	f.assertStatements(t,
+		"BEGIN",
+		"SAVEPOINT sp",
+		// the methods called on a transactional view run in their own savepoint
+		"SAVEPOINT sp", `UPDATE "lp_tx_infos"`, `INSERT INTO "lp_tx_events"`,
+		"ROLLBACK TO SAVEPOINT sp",
+		"SAVEPOINT sp", `UPDATE "lp_tx_infos"`, `INSERT INTO "lp_tx_events"`,
+		"COMMIT",
+	)
+	savepoint, _ := f.find("SAVEPOINT ")
+	rollback, _ := f.find("ROLLBACK TO SAVEPOINT ")
+	assert.Equal(t, strings.TrimPrefix(savepoint.query, "SAVEPOINT "), strings.TrimPrefix(rollback.query, "ROLLBACK TO SAVEPOINT "))
+
+	// a failed outer transaction rolls back everything
+	db, f = newFakeDatabase(t, nil)
+	assert.Equal(t, failed, db.WithTx(ctx, func(tx Store) error {
+		return failed
+	}))
+	f.assertStatements(t, "BEGIN", "ROLLBACK")
+}
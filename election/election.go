@@ -0,0 +1,231 @@
+// Package election elects a single leader among the instances of a service
+package election
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	sdkcontext "github.com/fiamma-chain/fiamma-go-sdk/context"
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/log"
+	"github.com/fiamma-chain/fiamma-go-sdk/utils"
+)
+
+// Backend holds the leadership, at most one instance may hold it at a time
+type Backend interface {
+	// Acquire tries to become the leader without waiting, and reports whether it is the leader
+	Acquire(ctx context.Context) (bool, error)
+	// Renew extends the leadership, false means it has been lost
+	Renew(ctx context.Context) (bool, error)
+	// Release gives up the leadership
+	Release(ctx context.Context) error
+}
+
+// Config election config
+type Config struct {
+	// RenewInterval is how often the leader renews its leadership
+	RenewInterval time.Duration `yaml:"renewInterval" json:"renewInterval" default:"5s"`
+	// RetryInterval is how often a follower tries to become the leader
+	RetryInterval time.Duration `yaml:"retryInterval" json:"retryInterval" default:"5s"`
+	// ReleaseTimeout bounds the release of the leadership when stepping down
+	ReleaseTimeout time.Duration `yaml:"releaseTimeout" json:"releaseTimeout" default:"5s"`
+	// LeaseDuration is how long the leadership lasts after an acquire or renew, it must not exceed
+	// the lease of the backend. The elector considers itself a follower once the lease is about
+	// to expire, even if Renew has not returned yet.
+	LeaseDuration time.Duration `yaml:"leaseDuration" json:"leaseDuration" default:"15s"`
+	// LeaseMargin is subtracted from LeaseDuration to bear with clock drift and network delays
+	LeaseMargin time.Duration `yaml:"leaseMargin" json:"leaseMargin" default:"1s"`
+}
+
+// Elector campaigns for the leadership until it is stopped
+type Elector struct {
+	backend Backend
+	cfg     Config
+	log     *log.Logger
+
+	mu      sync.Mutex
+	leader  bool
+	changes chan bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// New creates an elector, zero config values are set to their defaults
+func New(backend Backend, cfg Config) (*Elector, error) {
+	if err := utils.SetDefaults(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.RenewInterval >= cfg.LeaseDuration-cfg.LeaseMargin {
+		return nil, errors.New("renew interval must be shorter than the lease duration minus its margin")
+	}
+	return &Elector{
+		backend: backend,
+		cfg:     cfg,
+		log:     log.L().With(log.Any("service", "election")),
+		changes: make(chan bool, 1),
+	}, nil
+}
+
+// IsLeader reports whether this instance is currently the leader
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Changes returns a channel receiving the leadership every time it changes.
+// Only the latest value is kept if the receiver is slow.
+func (e *Elector) Changes() <-chan bool {
+	return e.changes
+}
+
+// Start campaigns in the background until ctx is done or Stop is called
+func (e *Elector) Start(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done != nil {
+		return
+	}
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+	go e.run(ctx, e.done)
+}
+
+// StartWithContext campaigns in the background and steps down once the service
+// receives SIGTERM or SIGINT, so that another instance can take over before this one exits.
+// The returned channel is closed once the elector stopped and the leadership is released,
+// the service should wait for it before exiting. The signals are unregistered once the elector stopped.
+func (e *Elector) StartWithContext(_ sdkcontext.Context) <-chan struct{} {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	e.Start(context.Background())
+	e.mu.Lock()
+	done := e.done
+	e.mu.Unlock()
+	go func() {
+		defer signal.Stop(sig)
+		select {
+		case s := <-sig:
+			e.log.Info("stepping down on signal", log.Any("signal", s.String()))
+			e.Stop()
+		case <-done:
+		}
+	}()
+	return done
+}
+
+// Stop steps down and waits until the leadership is released
+func (e *Elector) Stop() {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.mu.Unlock()
+	if done == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (e *Elector) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	// expireAt is when the leadership obtained by the last successful acquire or renew is considered lost
+	var expireAt time.Time
+	for {
+		interval := e.cfg.RetryInterval
+		if e.IsLeader() {
+			if e.renew(ctx, &expireAt) {
+				interval = e.cfg.RenewInterval
+			}
+		} else {
+			start := time.Now()
+			ok, err := e.backend.Acquire(ctx)
+			if err != nil && ctx.Err() == nil {
+				e.log.Warn("failed to acquire leadership", log.Error(err))
+			}
+			if ok {
+				expireAt = e.leaseExpiry(start)
+				e.log.Info("became the leader")
+				e.setLeader(true)
+				interval = e.cfg.RenewInterval
+			}
+		}
+		// wake up before the lease expires, so that an expired leadership is never reported
+		if e.IsLeader() {
+			if d := time.Until(expireAt); d < interval {
+				interval = d
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			e.stepDown()
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// renew extends the leadership and reports whether it is still held. Renew is bounded by
+// the lease expiry, since a renew returning after it cannot prevent another leader.
+func (e *Elector) renew(ctx context.Context, expireAt *time.Time) bool {
+	if !time.Now().Before(*expireAt) {
+		e.log.Warn("leadership lease expired")
+		e.setLeader(false)
+		return false
+	}
+	start := time.Now()
+	renewCtx, cancel := context.WithDeadline(ctx, *expireAt)
+	ok, err := e.backend.Renew(renewCtx)
+	cancel()
+	if err != nil && ctx.Err() == nil {
+		e.log.Warn("failed to renew leadership", log.Error(err))
+	}
+	if ok && time.Now().Before(*expireAt) {
+		*expireAt = e.leaseExpiry(start)
+		return true
+	}
+	if ctx.Err() != nil {
+		// stopping, stepDown releases the leadership
+		return true
+	}
+	e.log.Warn("leadership is lost")
+	e.setLeader(false)
+	return false
+}
+
+// leaseExpiry returns when a lease acquired or renewed at start is considered lost
+func (e *Elector) leaseExpiry(start time.Time) time.Time {
+	return start.Add(e.cfg.LeaseDuration - e.cfg.LeaseMargin)
+}
+
+func (e *Elector) stepDown() {
+	if !e.IsLeader() {
+		return
+	}
+	e.setLeader(false)
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.ReleaseTimeout)
+	defer cancel()
+	if err := e.backend.Release(ctx); err != nil {
+		e.log.Warn("failed to release leadership", log.Error(err))
+		return
+	}
+	e.log.Info("stepped down")
+}
+
+func (e *Elector) setLeader(leader bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leader == leader {
+		return
+	}
+	e.leader = leader
+	select {
+	case <-e.changes:
+	default:
+	}
+	e.changes <- leader
+}
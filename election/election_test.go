@@ -0,0 +1,119 @@
+package election
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockBackend struct {
+	mu       sync.Mutex
+	free     bool
+	renew    bool
+	released bool
+}
+
+func (b *mockBackend) Acquire(context.Context) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.free, nil
+}
+
+func (b *mockBackend) Renew(context.Context) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.renew, nil
+}
+
+func (b *mockBackend) Release(context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.released = true
+	return nil
+}
+
+func TestElector(t *testing.T) {
+	b := &mockBackend{free: true, renew: true}
+	e, err := New(b, Config{RenewInterval: 10 * time.Millisecond, RetryInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.False(t, e.IsLeader())
+
+	e.Start(context.Background())
+	assert.True(t, <-e.Changes())
+	assert.True(t, e.IsLeader())
+
+	b.mu.Lock()
+	b.free, b.renew = false, false
+	b.mu.Unlock()
+	assert.False(t, <-e.Changes())
+	assert.False(t, e.IsLeader())
+
+	b.mu.Lock()
+	b.free, b.renew = true, true
+	b.mu.Unlock()
+	assert.True(t, <-e.Changes())
+
+	e.Stop()
+	assert.False(t, <-e.Changes())
+	assert.False(t, e.IsLeader())
+	assert.True(t, b.released)
+}
+
+// hangingBackend acquires the leadership, but never returns from Renew before its deadline
+type hangingBackend struct {
+	mockBackend
+	deadline chan bool
+}
+
+func (b *hangingBackend) Renew(ctx context.Context) (bool, error) {
+	_, ok := ctx.Deadline()
+	b.deadline <- ok
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func TestElectorLeaseExpiry(t *testing.T) {
+	b := &hangingBackend{mockBackend: mockBackend{free: true}, deadline: make(chan bool, 10)}
+	e, err := New(b, Config{
+		RenewInterval: 10 * time.Millisecond,
+		RetryInterval: time.Hour,
+		LeaseDuration: 100 * time.Millisecond,
+		LeaseMargin:   20 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	e.Start(context.Background())
+	defer e.Stop()
+	assert.True(t, <-e.Changes())
+	assert.True(t, <-b.deadline)
+	assert.False(t, <-e.Changes())
+	// the leadership is given up before the lease expires, although Renew hangs
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestElectorConfig(t *testing.T) {
+	_, err := New(&mockBackend{}, Config{RenewInterval: 15 * time.Second, LeaseDuration: 15 * time.Second})
+	assert.Error(t, err)
+}
+
+func TestElectorStartWithContext(t *testing.T) {
+	b := &mockBackend{free: true, renew: true}
+	e, err := New(b, Config{RenewInterval: 10 * time.Millisecond, RetryInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+
+	done := e.StartWithContext(nil)
+	assert.True(t, <-e.Changes())
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+	<-done
+	assert.False(t, e.IsLeader())
+	b.mu.Lock()
+	assert.True(t, b.released)
+	b.mu.Unlock()
+}
@@ -1,6 +1,7 @@
 package ginctx
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"runtime/debug"
@@ -15,17 +16,52 @@ import (
 
 const (
 	LogKeyRequestID = "requestID"
+	// HeaderRequestID carries the request id to outbound calls
+	HeaderRequestID = "X-Request-Id"
 )
 
+type requestIDKey struct{}
+
 // Context context
 type Context struct {
 	*gin.Context
 	*log.Logger
+	requestID string
 }
 
 // NewContext create a new context with gin context
 func NewHttpContext(inner *gin.Context) *Context {
-	return &Context{inner, log.With(log.Any(LogKeyRequestID, uuid.NewV4().String()))}
+	id := uuid.NewV4().String()
+	inner.Set(LogKeyRequestID, id)
+	return &Context{inner, log.With(log.Any(LogKeyRequestID, id)), id}
+}
+
+// RequestID returns the id of the request
+func (c *Context) RequestID() string {
+	return c.requestID
+}
+
+// Ctx returns the context of the inbound request carrying the request id,
+// it is canceled once the client goes away and should be passed to outbound calls
+func (c *Context) Ctx() context.Context {
+	return WithRequestID(c.Request.Context(), c.requestID)
+}
+
+// WithRequestID returns a copy of ctx carrying the request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id carried by ctx, which is either set by WithRequestID
+// or ctx is a gin context handled by Wrapper. It returns an empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if id, ok := ctx.Value(LogKeyRequestID).(string); ok {
+		return id
+	}
+	return ""
 }
 
 // LoadBody loads json data from body into object and set defaults
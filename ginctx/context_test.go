@@ -0,0 +1,23 @@
+package ginctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHttpContextRequestID(t *testing.T) {
+	w := httptest.NewRecorder()
+	inner, _ := gin.CreateTestContext(w)
+	inner.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	inner.Request.Header.Set(HeaderRequestID, "inbound")
+
+	c := NewHttpContext(inner)
+	// the inbound request id is not reused
+	assert.Len(t, c.RequestID(), 36)
+	assert.Equal(t, c.RequestID(), RequestIDFromContext(c.Ctx()))
+	assert.Equal(t, c.RequestID(), RequestIDFromContext(inner))
+}
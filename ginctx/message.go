@@ -13,6 +13,8 @@ const (
 	ErrRequestAccessDenied   Code = "ErrRequestAccessDenied"
 	ErrRequestMethodNotFound      = "ErrRequestMethodNotFound"
 	ErrRequestParamInvalid        = "ErrRequestParamInvalid"
+	ErrRequestCanceled            = "ErrRequestCanceled"
+	ErrRequestTimeout             = "ErrRequestTimeout"
 	// * resource
 	ErrResourceNotFound        = "ErrResourceNotFound"
 	ErrResourceAccessForbidden = "ErrResourceAccessForbidden"
@@ -21,6 +23,8 @@ const (
 	ErrResourceHasBeenUsed     = "ErrResourceHasBeenUsed"
 	ErrInvalidToken            = "ErrInvalidToken"
 	ErrTooManyRequests         = "ErrTooManyRequests"
+	// * upstream
+	ErrUpstreamUnavailable = "ErrUpstreamUnavailable"
 
 	// * unknown
 	ErrUnknown = "UnknownError"
@@ -38,6 +42,10 @@ func getHTTPStatus(c Code) int {
 		return http.StatusConflict
 	case ErrTooManyRequests:
 		return http.StatusTooManyRequests
+	case ErrRequestCanceled, ErrUpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrRequestTimeout:
+		return http.StatusGatewayTimeout
 	case ErrUnknown:
 		return http.StatusInternalServerError
 	default:
@@ -0,0 +1,80 @@
+package ginctx
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/spec"
+)
+
+// LoadLpTxFilter parses the lp transaction filter and page from query parameters:
+// btcAddress, evmAddress, state (repeated or comma separated), minAmount, maxAmount,
+// createdAfter, createdBefore (RFC3339), cursor and limit
+func (c *Context) LoadLpTxFilter() (*spec.LpTxFilter, *spec.Page, error) {
+	filter := &spec.LpTxFilter{
+		BtcAddress: c.Query("btcAddress"),
+		EvmAddress: c.Query("evmAddress"),
+	}
+	for _, v := range c.QueryArray("state") {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			if !spec.IsValidLpTxState(s) {
+				return nil, nil, errors.CodeError(ErrRequestParamInvalid, "invalid state: "+s)
+			}
+			filter.States = append(filter.States, s)
+		}
+	}
+	var err error
+	if filter.MinAmount, err = c.queryDecimal("minAmount"); err != nil {
+		return nil, nil, err
+	}
+	if filter.MaxAmount, err = c.queryDecimal("maxAmount"); err != nil {
+		return nil, nil, err
+	}
+	if filter.CreatedAfter, err = c.queryTime("createdAfter"); err != nil {
+		return nil, nil, err
+	}
+	if filter.CreatedBefore, err = c.queryTime("createdBefore"); err != nil {
+		return nil, nil, err
+	}
+
+	page := &spec.Page{Cursor: c.Query("cursor")}
+	if v := c.Query("limit"); v != "" {
+		page.Limit, err = strconv.Atoi(v)
+		if err != nil || page.Limit < 0 {
+			return nil, nil, errors.CodeError(ErrRequestParamInvalid, "invalid limit: "+v)
+		}
+	}
+	return filter, page, nil
+}
+
+func (c *Context) queryDecimal(key string) (*decimal.Decimal, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return nil, errors.CodeError(ErrRequestParamInvalid, "invalid "+key+": "+v)
+	}
+	return &d, nil
+}
+
+func (c *Context) queryTime(key string) (*time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, errors.CodeError(ErrRequestParamInvalid, "invalid "+key+": "+v)
+	}
+	return &t, nil
+}
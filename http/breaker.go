@@ -0,0 +1,231 @@
+package http
+
+import (
+	gohttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/ginctx"
+)
+
+const (
+	BreakerStateClosed   = "closed"
+	BreakerStateOpen     = "open"
+	BreakerStateHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen indicates the request is rejected without being sent, since its host keeps failing
+var ErrCircuitOpen = errors.CodeError(ginctx.ErrUpstreamUnavailable, "circuit breaker is open")
+
+// BreakerConfig circuit breaker config, the breaker of a host opens once the failure rate
+// of the requests sent within Window reaches FailureRate. Connection errors and 5xx responses are failures.
+type BreakerConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Window is the period over which the failure rate is measured while the breaker is closed
+	Window time.Duration `yaml:"window" json:"window" default:"1m"`
+	// MinRequests is the number of requests within Window below which the breaker never opens
+	MinRequests int     `yaml:"minRequests" json:"minRequests" default:"20"`
+	FailureRate float64 `yaml:"failureRate" json:"failureRate" default:"0.5"`
+	// CoolDown is how long the breaker stays open before letting probe requests through
+	CoolDown time.Duration `yaml:"coolDown" json:"coolDown" default:"30s"`
+	// HalfOpenRequests is the number of successful probes which close the breaker again
+	HalfOpenRequests int `yaml:"halfOpenRequests" json:"halfOpenRequests" default:"1"`
+	// OnStateChange is called with the host and the states on every transition of a breaker,
+	// like to export metrics. It is called synchronously by the request, so it must not block.
+	OnStateChange func(host, from, to string) `yaml:"-" json:"-"`
+}
+
+// NewBreakerConfig creates an enabled breaker config with default values
+func NewBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		Enabled:          true,
+		Window:           time.Minute,
+		MinRequests:      20,
+		FailureRate:      0.5,
+		CoolDown:         30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+// withDefaults returns the config with the non-positive values and a failure rate above 1 set to their defaults,
+// zero values would open the breaker on the first request or never let a probe through
+func (cfg BreakerConfig) withDefaults() BreakerConfig {
+	def := NewBreakerConfig()
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = def.MinRequests
+	}
+	if cfg.FailureRate <= 0 || cfg.FailureRate > 1 {
+		cfg.FailureRate = def.FailureRate
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = def.CoolDown
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = def.HalfOpenRequests
+	}
+	return cfg
+}
+
+type breaker struct {
+	cfg  *BreakerConfig
+	host string
+
+	mu          sync.Mutex
+	state       string
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	// probes and successes count the requests in the half-open state
+	probes    int
+	successes int
+}
+
+func newBreaker(cfg *BreakerConfig, host string) *breaker {
+	return &breaker{cfg: cfg, host: host, state: BreakerStateClosed, windowStart: time.Now()}
+}
+
+// allow reports whether a request may be sent, which must then be recorded with done
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	from := b.state
+	err := b.allowLocked()
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return err
+}
+
+func (b *breaker) allowLocked() error {
+	now := time.Now()
+	switch b.state {
+	case BreakerStateOpen:
+		if now.Sub(b.openedAt) < b.cfg.CoolDown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerStateHalfOpen
+		b.probes, b.successes = 0, 0
+		fallthrough
+	case BreakerStateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	default:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	}
+	return nil
+}
+
+func (b *breaker) done(failed bool) {
+	b.mu.Lock()
+	from := b.state
+	b.doneLocked(failed)
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+func (b *breaker) doneLocked(failed bool) {
+	switch b.state {
+	case BreakerStateHalfOpen:
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.state = BreakerStateClosed
+			b.windowStart, b.requests, b.failures = time.Now(), 0, 0
+		}
+	case BreakerStateClosed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.cfg.MinRequests && float64(b.failures) >= b.cfg.FailureRate*float64(b.requests) {
+			b.open()
+		}
+	}
+}
+
+// abort gives back an allowed request which is not recorded
+func (b *breaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerStateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// notify reports a transition to the hook, it must be called without b.mu held
+func (b *breaker) notify(from, to string) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.host, from, to)
+	}
+}
+
+func (b *breaker) open() {
+	b.state = BreakerStateOpen
+	b.openedAt = time.Now()
+}
+
+func (b *breaker) currentState() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerStateOpen && time.Since(b.openedAt) >= b.cfg.CoolDown {
+		return BreakerStateHalfOpen
+	}
+	return b.state
+}
+
+// breaker returns the breaker of the host, nil if breakers are disabled
+func (c *Client) breaker(host string) *breaker {
+	if !c.breakerCfg.Enabled {
+		return nil
+	}
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	b, ok := c.breakers[host]
+	if !ok {
+		b = newBreaker(&c.breakerCfg, host)
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// BreakerStates returns the breaker state of every host requested so far, keyed by host
+func (c *Client) BreakerStates() map[string]string {
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	states := make(map[string]string, len(c.breakers))
+	for host, b := range c.breakers {
+		states[host] = b.currentState()
+	}
+	return states
+}
+
+// send sends a single attempt through the breaker of the host
+func (c *Client) send(req *gohttp.Request) (*gohttp.Response, error) {
+	b := c.breaker(req.URL.Host)
+	if b == nil {
+		return c.http.Do(req)
+	}
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	r, err := c.http.Do(req)
+	// canceled requests say nothing about the health of the host
+	if err != nil && req.Context().Err() != nil {
+		b.abort()
+		return r, err
+	}
+	b.done(err != nil || r.StatusCode >= gohttp.StatusInternalServerError)
+	return r, err
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	gohttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+)
+
+func TestClientBreaker(t *testing.T) {
+	status := gohttp.StatusInternalServerError
+	var calls int
+	ts := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	ops := NewClientOptions()
+	ops.Breaker = NewBreakerConfig()
+	ops.Breaker.MinRequests = 4
+	ops.Breaker.CoolDown = 50 * time.Millisecond
+	var transitions []string
+	ops.Breaker.OnStateChange = func(host, from, to string) {
+		assert.Equal(t, u.Host, host)
+		transitions = append(transitions, from+">"+to)
+	}
+	cli := NewClient(ops)
+
+	for i := 0; i < 4; i++ {
+		_, err := cli.GetJSON(ts.URL)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, BreakerStateOpen, cli.BreakerStates()[u.Host])
+
+	_, err := cli.GetJSON(ts.URL)
+	assert.Equal(t, ErrCircuitOpen, errors.Cause(err))
+	assert.Equal(t, 4, calls)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, BreakerStateHalfOpen, cli.BreakerStates()[u.Host])
+	_, err = cli.GetJSON(ts.URL)
+	assert.Error(t, err)
+	assert.Equal(t, BreakerStateOpen, cli.BreakerStates()[u.Host])
+
+	time.Sleep(60 * time.Millisecond)
+	status = gohttp.StatusOK
+	_, err = cli.GetJSON(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, BreakerStateClosed, cli.BreakerStates()[u.Host])
+	assert.Equal(t, []string{
+		"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed",
+	}, transitions)
+}
+
+func TestClientBreakerZeroConfig(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		calls++
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	ops := NewClientOptions()
+	ops.Breaker = BreakerConfig{Enabled: true}
+	cli := NewClient(ops)
+	for i := 0; i < 30; i++ {
+		_, err := cli.GetJSON(ts.URL)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 30, calls)
+	assert.Equal(t, BreakerStateClosed, cli.BreakerStates()[u.Host])
+
+	cfg := BreakerConfig{Enabled: true, FailureRate: 2, HalfOpenRequests: -1}.withDefaults()
+	assert.Equal(t, NewBreakerConfig(), cfg)
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,12 +10,14 @@ import (
 	gohttp "net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/conduitio/bwlimit"
 	"github.com/panjf2000/ants/v2"
 
 	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+	"github.com/fiamma-chain/fiamma-go-sdk/ginctx"
 	"github.com/fiamma-chain/fiamma-go-sdk/log"
 )
 
@@ -23,9 +26,15 @@ var formHeaders = map[string]string{"Content-Type": "application/x-www-form-urle
 
 // Client client of http server
 type Client struct {
-	ops     *ClientOptions
-	http    *gohttp.Client
-	antPool *ants.Pool
+	ops       *ClientOptions
+	http      *gohttp.Client
+	transport *gohttp.Transport
+	antPool   *ants.Pool
+
+	// breakerCfg is ops.Breaker with its defaults applied
+	breakerCfg BreakerConfig
+	breakersMu sync.Mutex
+	breakers   map[string]*breaker
 }
 
 // NewClient creates a new http client
@@ -77,9 +86,12 @@ func NewClient(ops *ClientOptions) *Client {
 		ops: ops,
 		http: &gohttp.Client{
 			Timeout:   ops.Timeout,
-			Transport: transport,
+			Transport: chainInterceptors(transport, ops.Interceptors),
 		},
-		antPool: p,
+		transport:  transport,
+		antPool:    p,
+		breakerCfg: ops.Breaker.withDefaults(),
+		breakers:   map[string]*breaker{},
 	}
 }
 
@@ -88,12 +100,17 @@ func (c *Client) SetBwlimit(writeLimit, readLimit bwlimit.Byte) {
 		Timeout:   c.ops.Timeout,
 		KeepAlive: c.ops.KeepAlive,
 	}, writeLimit*bwlimit.Mebibyte, readLimit*bwlimit.KB)
-	c.http.Transport.(*gohttp.Transport).DialContext = dialer.DialContext
+	c.transport.DialContext = dialer.DialContext
 }
 
 // Call calls the function via HTTP POST
 func (c *Client) Call(function string, payload []byte) ([]byte, error) {
-	r, err := c.PostURL(function, bytes.NewBuffer(payload), jsonHeaders)
+	return c.CallCtx(context.Background(), function, payload)
+}
+
+// CallCtx calls the function via HTTP POST with ctx
+func (c *Client) CallCtx(ctx context.Context, function string, payload []byte) ([]byte, error) {
+	r, err := c.PostURLCtx(ctx, function, bytes.NewBuffer(payload), jsonHeaders)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -102,8 +119,13 @@ func (c *Client) Call(function string, payload []byte) ([]byte, error) {
 
 // PostJSON post data with json content type
 func (c *Client) PostJSON(url string, payload []byte, headers ...map[string]string) ([]byte, error) {
+	return c.PostJSONCtx(context.Background(), url, payload, headers...)
+}
+
+// PostJSONCtx post data with json content type with ctx
+func (c *Client) PostJSONCtx(ctx context.Context, url string, payload []byte, headers ...map[string]string) ([]byte, error) {
 	headers = append(headers, jsonHeaders)
-	r, err := c.PostURL(url, bytes.NewBuffer(payload), headers...)
+	r, err := c.PostURLCtx(ctx, url, bytes.NewBuffer(payload), headers...)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -112,8 +134,13 @@ func (c *Client) PostJSON(url string, payload []byte, headers ...map[string]stri
 
 // GetJSON get data with json content type
 func (c *Client) GetJSON(url string, headers ...map[string]string) ([]byte, error) {
+	return c.GetJSONCtx(context.Background(), url, headers...)
+}
+
+// GetJSONCtx get data with json content type with ctx
+func (c *Client) GetJSONCtx(ctx context.Context, url string, headers ...map[string]string) ([]byte, error) {
 	headers = append(headers, jsonHeaders)
-	r, err := c.GetURL(url, headers...)
+	r, err := c.GetURLCtx(ctx, url, headers...)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -121,18 +148,34 @@ func (c *Client) GetJSON(url string, headers ...map[string]string) ([]byte, erro
 }
 
 func (c *Client) GetURL(url string, header ...map[string]string) (*gohttp.Response, error) {
-	return c.SendUrl("GET", url, nil, header...)
+	return c.GetURLCtx(context.Background(), url, header...)
+}
+
+func (c *Client) GetURLCtx(ctx context.Context, url string, header ...map[string]string) (*gohttp.Response, error) {
+	return c.SendUrlCtx(ctx, "GET", url, nil, header...)
 }
 
 func (c *Client) DeleteURL(url string, header ...map[string]string) (*gohttp.Response, error) {
-	return c.SendUrl("DELETE", url, nil, header...)
+	return c.DeleteURLCtx(context.Background(), url, header...)
+}
+
+func (c *Client) DeleteURLCtx(ctx context.Context, url string, header ...map[string]string) (*gohttp.Response, error) {
+	return c.SendUrlCtx(ctx, "DELETE", url, nil, header...)
 }
 
 func (c *Client) PostURL(url string, body io.Reader, header ...map[string]string) (*gohttp.Response, error) {
-	return c.SendUrl("POST", url, body, header...)
+	return c.PostURLCtx(context.Background(), url, body, header...)
+}
+
+func (c *Client) PostURLCtx(ctx context.Context, url string, body io.Reader, header ...map[string]string) (*gohttp.Response, error) {
+	return c.SendUrlCtx(ctx, "POST", url, body, header...)
 }
 
 func (c *Client) PostURLWithParams(urlStr string, params map[string]string, header ...map[string]string) ([]byte, error) {
+	return c.PostURLWithParamsCtx(context.Background(), urlStr, params, header...)
+}
+
+func (c *Client) PostURLWithParamsCtx(ctx context.Context, urlStr string, params map[string]string, header ...map[string]string) ([]byte, error) {
 	header = append(header, formHeaders)
 
 	d := url.Values{}
@@ -141,7 +184,7 @@ func (c *Client) PostURLWithParams(urlStr string, params map[string]string, head
 	}
 	form := d.Encode()
 
-	r, err := c.PostURL(urlStr, strings.NewReader(form), header...)
+	r, err := c.PostURLCtx(ctx, urlStr, strings.NewReader(form), header...)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -149,32 +192,49 @@ func (c *Client) PostURLWithParams(urlStr string, params map[string]string, head
 }
 
 func (c *Client) PutURL(url string, body io.Reader, header ...map[string]string) (*gohttp.Response, error) {
-	return c.SendUrl("PUT", url, body, header...)
+	return c.PutURLCtx(context.Background(), url, body, header...)
+}
+
+func (c *Client) PutURLCtx(ctx context.Context, url string, body io.Reader, header ...map[string]string) (*gohttp.Response, error) {
+	return c.SendUrlCtx(ctx, "PUT", url, body, header...)
 }
 
 func (c *Client) SendUrl(method, url string, body io.Reader, header ...map[string]string) (*gohttp.Response, error) {
+	return c.SendUrlCtx(context.Background(), method, url, body, header...)
+}
+
+// SendUrlCtx sends the request, which is aborted once ctx is done.
+// The request id carried by ctx, see ginctx.WithRequestID, is sent in the ginctx.HeaderRequestID header.
+func (c *Client) SendUrlCtx(ctx context.Context, method, url string, body io.Reader, header ...map[string]string) (*gohttp.Response, error) {
 	if !strings.HasPrefix(url, "http") {
 		url = fmt.Sprintf("%s/%s", c.ops.Address, url)
 	}
-	req, err := gohttp.NewRequest(method, url, body)
+	req, err := gohttp.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	if id := ginctx.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(ginctx.HeaderRequestID, id)
+	}
 	for _, v := range header {
 		for kk, vv := range v {
 			req.Header.Set(kk, vv)
 		}
 	}
-	r, err := c.http.Do(req)
+	r, err := c.do(req)
 	return r, errors.Trace(err)
 }
 
 func (c *Client) SyncSendUrl(method, url string, body io.Reader, syncResult chan *SyncResults, extra map[string]interface{}, header ...map[string]string) {
+	c.SyncSendUrlCtx(context.Background(), method, url, body, syncResult, extra, header...)
+}
+
+func (c *Client) SyncSendUrlCtx(ctx context.Context, method, url string, body io.Reader, syncResult chan *SyncResults, extra map[string]interface{}, header ...map[string]string) {
 	SyncSendStart := time.Now()
 	err := c.antPool.Submit(
 		func() {
 			sendStart := time.Now()
-			response, err := c.SendUrl(method, url, body, header...)
+			response, err := c.SendUrlCtx(ctx, method, url, body, header...)
 			sendElapsed := time.Since(sendStart)
 			syncElapsed := time.Since(SyncSendStart)
 
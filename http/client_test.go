@@ -0,0 +1,43 @@
+package http
+
+import (
+	"context"
+	gohttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/ginctx"
+)
+
+func TestClientCtx(t *testing.T) {
+	ts := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(r.Header.Get(ginctx.HeaderRequestID)))
+	}))
+	defer ts.Close()
+	cli := NewClient(NewClientOptions())
+
+	data, err := cli.GetJSONCtx(ginctx.WithRequestID(context.Background(), "req-1"), ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "req-1", string(data))
+
+	data, err = cli.GetJSON(ts.URL)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = cli.GetJSONCtx(ctx, ts.URL+"/slow")
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"context"
+	gohttp "net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/log"
+	"github.com/fiamma-chain/fiamma-go-sdk/utils"
+)
+
+// Interceptor wraps the round tripper of the client to inject cross-cutting behavior.
+// Interceptors run once per attempt, and must not modify the request, but a clone of it.
+type Interceptor func(next gohttp.RoundTripper) gohttp.RoundTripper
+
+// RoundTripperFunc adapts a function to a round tripper
+type RoundTripperFunc func(req *gohttp.Request) (*gohttp.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *gohttp.Request) (*gohttp.Response, error) {
+	return f(req)
+}
+
+// chainInterceptors wraps rt with the interceptors, the first interceptor is the outermost one
+func chainInterceptors(rt gohttp.RoundTripper, interceptors []Interceptor) gohttp.RoundTripper {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		rt = interceptors[i](rt)
+	}
+	return rt
+}
+
+// LoggingInterceptor logs every request with its status and elapsed time, failed ones at warn level.
+// The query and the password of the url are left out, since they may carry credentials.
+func LoggingInterceptor(logger *log.Logger) Interceptor {
+	if logger == nil {
+		logger = log.L().With(log.Any("service", "http_client"))
+	}
+	return func(next gohttp.RoundTripper) gohttp.RoundTripper {
+		return RoundTripperFunc(func(req *gohttp.Request) (*gohttp.Response, error) {
+			start := time.Now()
+			r, err := next.RoundTrip(req)
+			fields := []log.Field{
+				log.Any("method", req.Method),
+				log.Any("url", redactURL(req.URL)),
+				log.Any("elapsed", time.Since(start).String()),
+			}
+			if err != nil {
+				logger.Warn("http request failed", append(fields, log.Error(err))...)
+				return r, err
+			}
+			logger.Debug("http request", append(fields, log.Any("status", r.StatusCode))...)
+			return r, err
+		})
+	}
+}
+
+// redactURL returns the url without its query and with its password masked
+func redactURL(u *url.URL) string {
+	n := *u
+	n.RawQuery = ""
+	n.ForceQuery = false
+	return n.Redacted()
+}
+
+// HeaderInterceptor sets static headers on every request
+func HeaderInterceptor(headers map[string]string) Interceptor {
+	return DynamicHeaderInterceptor(func(*gohttp.Request) (map[string]string, error) {
+		return headers, nil
+	})
+}
+
+// DynamicHeaderInterceptor sets the headers returned by fn on every request, like signatures,
+// the request is not sent if fn returns an error
+func DynamicHeaderInterceptor(fn func(req *gohttp.Request) (map[string]string, error)) Interceptor {
+	return func(next gohttp.RoundTripper) gohttp.RoundTripper {
+		return RoundTripperFunc(func(req *gohttp.Request) (*gohttp.Response, error) {
+			headers, err := fn(req)
+			if err != nil {
+				closeBody(req)
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// TokenSource provides bearer tokens
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// BearerTokenInterceptor sets the Authorization header with a token of the source
+func BearerTokenInterceptor(source TokenSource) Interceptor {
+	return DynamicHeaderInterceptor(func(req *gohttp.Request) (map[string]string, error) {
+		token, err := source.Token(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"Authorization": "Bearer " + token}, nil
+	})
+}
+
+// JWTTokenSource issues tokens with a JWTHelper, and issues a new one once the current one
+// is about to expire
+type JWTTokenSource struct {
+	helper        *utils.JWTHelper
+	claims        map[string]interface{}
+	refreshBefore time.Duration
+
+	mu     sync.Mutex
+	token  string
+	expire time.Time
+}
+
+// NewJWTTokenSource creates a token source issuing tokens with the claims,
+// tokens are renewed refreshBefore their expiry
+func NewJWTTokenSource(helper *utils.JWTHelper, claims map[string]interface{}, refreshBefore time.Duration) *JWTTokenSource {
+	return &JWTTokenSource{
+		helper:        helper,
+		claims:        claims,
+		refreshBefore: refreshBefore,
+	}
+}
+
+func (s *JWTTokenSource) Token(_ context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Add(s.refreshBefore).Before(s.expire) {
+		return s.token, nil
+	}
+	// Generate adds the expiry to the claims, never share them between tokens
+	claims := make(map[string]interface{}, len(s.claims)+2)
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+	token, expire, err := s.helper.Generate(claims)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.expire = token, expire
+	return token, nil
+}
+
+// TimingInterceptor reports the elapsed time of every request, status is 0 if the request failed
+func TimingInterceptor(fn func(req *gohttp.Request, status int, elapsed time.Duration, err error)) Interceptor {
+	return func(next gohttp.RoundTripper) gohttp.RoundTripper {
+		return RoundTripperFunc(func(req *gohttp.Request) (*gohttp.Response, error) {
+			start := time.Now()
+			r, err := next.RoundTrip(req)
+			status := 0
+			if r != nil {
+				status = r.StatusCode
+			}
+			fn(req, status, time.Since(start), err)
+			return r, err
+		})
+	}
+}
+
+// closeBody closes the body of a request which is not sent, as round trippers must do
+func closeBody(req *gohttp.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	gohttp "net/http"
+	"strings"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+)
+
+// RemoteError is the {code,message} error body of a failed response,
+// as emitted by RespondMsg and ginctx.PopulateFailedResponse
+type RemoteError struct {
+	StatusCode int
+	ErrCode    string
+	Message    string
+}
+
+// Code returns the remote error code, so that RemoteError is an errors.Coder
+func (e *RemoteError) Code() string {
+	return e.ErrCode
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("[%d] %s: %s", e.StatusCode, e.ErrCode, e.Message)
+}
+
+func (e *RemoteError) Format(s fmt.State, _ rune) {
+	io.WriteString(s, e.Error()) //nolint:errcheck
+}
+
+// DoJSON sends req as json and decodes the successful response into Resp.
+// A failed response with a {code,message} body is returned as a *RemoteError.
+func DoJSON[Req, Resp any](ctx context.Context, c *Client, method, url string, req Req, headers ...map[string]string) (Resp, error) {
+	var resp Resp
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return resp, errors.Trace(err)
+	}
+	headers = append(headers, jsonHeaders)
+	r, err := c.SendUrlCtx(ctx, method, url, bytes.NewReader(payload), headers...)
+	if err != nil {
+		return resp, errors.Trace(err)
+	}
+	return decodeJSON[Resp](r)
+}
+
+// GetJSONAs gets url and decodes the successful response into Resp, see DoJSON
+func GetJSONAs[Resp any](ctx context.Context, c *Client, url string, headers ...map[string]string) (Resp, error) {
+	headers = append(headers, jsonHeaders)
+	r, err := c.GetURLCtx(ctx, url, headers...)
+	if err != nil {
+		var resp Resp
+		return resp, errors.Trace(err)
+	}
+	return decodeJSON[Resp](r)
+}
+
+// PostJSONAs posts req as json and decodes the successful response into Resp, see DoJSON
+func PostJSONAs[Req, Resp any](ctx context.Context, c *Client, url string, req Req, headers ...map[string]string) (Resp, error) {
+	return DoJSON[Req, Resp](ctx, c, gohttp.MethodPost, url, req, headers...)
+}
+
+func decodeJSON[Resp any](r *gohttp.Response) (Resp, error) {
+	var resp Resp
+	defer r.Body.Close()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return resp, errors.Trace(err)
+	}
+	if r.StatusCode < gohttp.StatusOK || r.StatusCode > gohttp.StatusAlreadyReported {
+		return resp, parseRemoteError(r, data)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return resp, nil
+	}
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return resp, errors.Errorf("failed to decode response: %s", err.Error())
+	}
+	return resp, nil
+}
+
+func parseRemoteError(r *gohttp.Response, data []byte) error {
+	var body Response
+	if json.Unmarshal(data, &body) == nil && body.Code != "" {
+		return &RemoteError{StatusCode: r.StatusCode, ErrCode: body.Code, Message: body.Message}
+	}
+	msg := strings.TrimRight(string(data), "\n")
+	if msg == "" {
+		msg = r.Status
+	}
+	return errors.Errorf("[%d] %s", r.StatusCode, msg)
+}
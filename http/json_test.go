@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	gohttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/errors"
+)
+
+func TestDoJSON(t *testing.T) {
+	type echo struct {
+		Name string `json:"name"`
+	}
+	ts := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			var e echo
+			json.NewDecoder(r.Body).Decode(&e)
+			json.NewEncoder(w).Encode(e)
+		case "/missing":
+			w.WriteHeader(gohttp.StatusNotFound)
+			json.NewEncoder(w).Encode(NewResponse("ErrResourceNotFound", "not found"))
+		default:
+			w.WriteHeader(gohttp.StatusInternalServerError)
+			w.Write([]byte("boom"))
+		}
+	}))
+	defer ts.Close()
+	cli := NewClient(NewClientOptions())
+	ctx := context.Background()
+
+	resp, err := PostJSONAs[echo, echo](ctx, cli, ts.URL+"/echo", echo{Name: "lp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "lp", resp.Name)
+
+	_, err = GetJSONAs[echo](ctx, cli, ts.URL+"/missing")
+	coder, ok := err.(errors.Coder)
+	assert.True(t, ok)
+	assert.Equal(t, "ErrResourceNotFound", coder.Code())
+	assert.Equal(t, gohttp.StatusNotFound, err.(*RemoteError).StatusCode)
+
+	_, err = DoJSON[echo, echo](ctx, cli, gohttp.MethodPut, ts.URL+"/fail", echo{})
+	assert.EqualError(t, err, "[500] boom")
+}
@@ -50,6 +50,10 @@ type ClientOptions struct {
 	SpeedLimit            int
 	ByteUnit              string
 	SyncMaxConcurrency    int
+	Retry                 RetryPolicy
+	Breaker               BreakerConfig
+	// Interceptors wrap every attempt of every request, the first one is the outermost
+	Interceptors []Interceptor
 }
 
 // NewClientOptions creates client options with default values
@@ -61,6 +65,7 @@ func NewClientOptions() *ClientOptions {
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
+		Retry:                 NewRetryPolicy(1),
 	}
 }
 
@@ -76,6 +81,8 @@ type ClientConfig struct {
 	ByteUnit              string        `yaml:"byteUnit" json:"byteUnit" default:"KB"`
 	SpeedLimit            int           `yaml:"speedLimit" json:"speedLimit" default:"0"`
 	SyncMaxConcurrency    int           `yaml:"syncMaxConcurrency" json:"syncMaxConcurrency" default:"0"`
+	Retry                 RetryPolicy   `yaml:"retry" json:"retry"`
+	Breaker               BreakerConfig `yaml:"breaker" json:"breaker"`
 	utils.Certificate     `yaml:",inline" json:",inline"`
 }
 
@@ -97,6 +104,8 @@ func (cc ClientConfig) ToClientOptions() (*ClientOptions, error) {
 		SpeedLimit:            cc.SpeedLimit,
 		ByteUnit:              cc.ByteUnit,
 		SyncMaxConcurrency:    cc.SyncMaxConcurrency,
+		Retry:                 cc.Retry,
+		Breaker:               cc.Breaker,
 	}, nil
 }
 
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	gohttp "net/http"
+	"strconv"
+	"time"
+)
+
+// IdempotencyKeyHeader marks a request as safe to retry whatever its method
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy retry policy of client requests, requests are sent once if MaxAttempts is at most 1
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request, including the first one
+	MaxAttempts int `yaml:"maxAttempts" json:"maxAttempts" default:"1"`
+	// MinBackoff and MaxBackoff bound the exponential delay between attempts, a random jitter is applied
+	MinBackoff time.Duration `yaml:"minBackoff" json:"minBackoff" default:"100ms"`
+	MaxBackoff time.Duration `yaml:"maxBackoff" json:"maxBackoff" default:"10s"`
+	// RetryableStatuses are the response status codes to retry, connection errors are always retried
+	RetryableStatuses []int `yaml:"retryableStatuses" json:"retryableStatuses" default:"[429,502,503,504]"`
+	// RetryNonIdempotent also retries methods like POST, which are only retried by default
+	// if they carry an Idempotency-Key header
+	RetryNonIdempotent bool `yaml:"retryNonIdempotent" json:"retryNonIdempotent"`
+}
+
+// NewRetryPolicy creates a retry policy with default values and the given max attempts
+func NewRetryPolicy(maxAttempts int) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       maxAttempts,
+		MinBackoff:        100 * time.Millisecond,
+		MaxBackoff:        10 * time.Second,
+		RetryableStatuses: []int{gohttp.StatusTooManyRequests, gohttp.StatusBadGateway, gohttp.StatusServiceUnavailable, gohttp.StatusGatewayTimeout},
+	}
+}
+
+func (p *RetryPolicy) enabled(req *gohttp.Request) bool {
+	if p.MaxAttempts <= 1 {
+		return false
+	}
+	if p.RetryNonIdempotent || req.Header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+	switch req.Method {
+	case gohttp.MethodGet, gohttp.MethodHead, gohttp.MethodOptions, gohttp.MethodTrace, gohttp.MethodPut, gohttp.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether the result of an attempt is worth retrying
+func (p *RetryPolicy) retryable(ctx context.Context, r *gohttp.Response, err error) bool {
+	if err != nil {
+		return err != ErrCircuitOpen && ctx.Err() == nil
+	}
+	for _, status := range p.RetryableStatuses {
+		if r.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the next attempt, Retry-After takes precedence.
+// False is returned if the server asks to wait longer than MaxBackoff.
+func (p *RetryPolicy) backoff(attempt int, r *gohttp.Response) (time.Duration, bool) {
+	if r != nil {
+		if d, ok := parseRetryAfter(r.Header.Get("Retry-After")); ok {
+			return d, d <= p.MaxBackoff
+		}
+	}
+	d := p.MinBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	// equal jitter, wait between half and the full delay
+	if half := int64(d / 2); half > 0 {
+		d = time.Duration(half + rand.Int63n(half+1))
+	}
+	return d, true
+}
+
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := gohttp.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// rewindable makes sure the body of the request can be sent again
+func rewindable(req *gohttp.Request) error {
+	if req.Body == nil || req.GetBody != nil {
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// do sends the request, and retries it according to the retry policy
+func (c *Client) do(req *gohttp.Request) (*gohttp.Response, error) {
+	policy := &c.ops.Retry
+	if !policy.enabled(req) {
+		return c.send(req)
+	}
+	if err := rewindable(req); err != nil {
+		return nil, err
+	}
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		r, err := c.send(req)
+		if attempt >= policy.MaxAttempts || !policy.retryable(ctx, r, err) {
+			return r, err
+		}
+		wait, ok := policy.backoff(attempt, r)
+		if !ok {
+			return r, err
+		}
+		if r != nil {
+			io.Copy(io.Discard, r.Body) //nolint:errcheck
+			r.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"io"
+	gohttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fiamma-chain/fiamma-go-sdk/utils"
+)
+
+func TestClientRetry(t *testing.T) {
+	var calls int
+	var bodies []string
+	ts := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		calls++
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(gohttp.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var cfg ClientConfig
+	assert.NoError(t, utils.UnmarshalYAML(nil, &cfg))
+	assert.Equal(t, 1, cfg.Retry.MaxAttempts)
+	assert.Equal(t, []int{429, 502, 503, 504}, cfg.Retry.RetryableStatuses)
+
+	ops := NewClientOptions()
+	ops.Retry = NewRetryPolicy(3)
+	cli := NewClient(ops)
+
+	// POST is not retried by default
+	_, err := cli.PostJSON(ts.URL, []byte(`{"a":1}`))
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	calls, bodies = 0, nil
+	data, err := cli.PostJSON(ts.URL, []byte(`{"a":1}`), map[string]string{IdempotencyKeyHeader: "k"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(data))
+	assert.Equal(t, []string{`{"a":1}`, `{"a":1}`, `{"a":1}`}, bodies)
+
+	calls = 0
+	ops.Retry.MaxAttempts = 2
+	_, err = cli.GetJSON(ts.URL)
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := NewRetryPolicy(5)
+	for attempt := 1; attempt <= 10; attempt++ {
+		d, ok := p.backoff(attempt, nil)
+		assert.True(t, ok)
+		assert.True(t, d <= p.MaxBackoff)
+		assert.True(t, d >= p.MinBackoff/2)
+	}
+
+	r := &gohttp.Response{Header: gohttp.Header{}}
+	r.Header.Set("Retry-After", "2")
+	d, ok := p.backoff(1, r)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, d)
+	r.Header.Set("Retry-After", "60")
+	_, ok = p.backoff(1, r)
+	assert.False(t, ok)
+}
@@ -0,0 +1,40 @@
+package spec
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// LpTxFilter filters lp transactions, zero fields are ignored
+type LpTxFilter struct {
+	BtcAddress    string
+	EvmAddress    string
+	States        []string
+	MinAmount     *decimal.Decimal
+	MaxAmount     *decimal.Decimal
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// Page describes a keyset page, Cursor is the token returned with the previous page
+type Page struct {
+	Cursor string
+	Limit  int
+}
+
+// GetLimit returns the page limit bounded by MaxPageLimit
+func (p *Page) GetLimit() int {
+	if p == nil || p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return p.Limit
+}
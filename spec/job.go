@@ -0,0 +1,32 @@
+package spec
+
+import (
+	"time"
+)
+
+const (
+	JobStatePending = "pending"
+	JobStateRunning = "running"
+	JobStateDone    = "done"
+	// JobStateDead is set once a job exhausted its attempts, it is kept for inspection
+	JobStateDead = "dead"
+)
+
+// Job is a durable unit of work of a queue
+type Job struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Queue     string    `gorm:"not null" json:"queue"`
+	// Payload is the json encoded job arguments
+	Payload     string `gorm:"type:text;not null;default:''" json:"payload"`
+	State       string `gorm:"not null;default:'pending'" json:"state"`
+	Attempts    int    `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int    `gorm:"not null" json:"maxAttempts"`
+	// RunAt is the earliest time the job runs at
+	RunAt time.Time `gorm:"not null" json:"runAt"`
+	// LockedUntil is when a running job is considered abandoned and runs again
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+	LastError   string     `gorm:"type:text;not null;default:''" json:"lastError"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
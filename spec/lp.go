@@ -13,6 +13,32 @@ const (
 	LpTxStateSuccess    = "success"
 )
 
+// LpTxStateTransitions lists the legal target states of every lp transaction state,
+// invalid and success are terminal
+var LpTxStateTransitions = map[string][]string{
+	LpTxStateCreated:    {LpTxStatePending, LpTxStateProcessing, LpTxStateInvalid},
+	LpTxStatePending:    {LpTxStateProcessing, LpTxStateInvalid},
+	LpTxStateProcessing: {LpTxStatePending, LpTxStateSuccess, LpTxStateInvalid},
+	LpTxStateInvalid:    {},
+	LpTxStateSuccess:    {},
+}
+
+// IsValidLpTxState checks whether the state is a known lp transaction state
+func IsValidLpTxState(state string) bool {
+	_, ok := LpTxStateTransitions[state]
+	return ok
+}
+
+// CanTransitLpTxState checks whether an lp transaction may move from one state to another
+func CanTransitLpTxState(from, to string) bool {
+	for _, s := range LpTxStateTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
 type LpTxInfo struct {
 	gorm.Model
 	TxHash     string          `gorm:"not null;uniqueIndex"`
@@ -20,4 +46,6 @@ type LpTxInfo struct {
 	EvmAddress string          `gorm:"not null;type:citext;index"`
 	Amount     decimal.Decimal `gorm:"type:decimal(24,6);default:0"`
 	State      string          `gorm:"not null;default:'created'"`
+	// Version is increased by every update, updates are conditional on the version the caller read
+	Version uint `gorm:"not null;default:1"`
 }
@@ -0,0 +1,28 @@
+package spec
+
+import (
+	"time"
+)
+
+const (
+	LpTxActionCreate     = "create"
+	LpTxActionUpdate     = "update"
+	LpTxActionTransition = "transition"
+	LpTxActionDelete     = "delete"
+	LpTxActionRestore    = "restore"
+	LpTxActionPurge      = "purge"
+)
+
+// LpTxEvent is an append-only audit record of a change made to an LpTxInfo
+type LpTxEvent struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null;index"`
+	TxHash    string    `gorm:"not null;index"`
+	Action    string    `gorm:"not null"`
+	Operator  string    `gorm:"not null;default:''"`
+	FromState string    `gorm:"not null;default:''"`
+	ToState   string    `gorm:"not null;default:''"`
+	// Before and After are json snapshots of the lp transaction, empty if not applicable
+	Before string `gorm:"type:text;not null;default:''"`
+	After  string `gorm:"type:text;not null;default:''"`
+}
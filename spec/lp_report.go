@@ -0,0 +1,35 @@
+package spec
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	IntervalHour  = "hour"
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// LpTxStateTotal is the count and amount total of lp transactions in a state
+type LpTxStateTotal struct {
+	State  string          `json:"state"`
+	Count  int64           `json:"count"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// LpTxBucket is the count and amount total of lp transactions created in [Start, Start + interval)
+type LpTxBucket struct {
+	Start  time.Time       `json:"start"`
+	Count  int64           `json:"count"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// LpTxDepositor is the count and amount total of lp transactions of a btc address
+type LpTxDepositor struct {
+	BtcAddress string          `json:"btcAddress"`
+	Count      int64           `json:"count"`
+	Amount     decimal.Decimal `json:"amount"`
+}
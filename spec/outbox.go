@@ -0,0 +1,35 @@
+package spec
+
+import (
+	"time"
+)
+
+const (
+	OutboxStatePending   = "pending"
+	OutboxStateDelivered = "delivered"
+	// OutboxStateFailed is set once a message exhausted its delivery attempts
+	OutboxStateFailed = "failed"
+)
+
+// OutboxTopicLpTxEvent is the topic of messages carrying the LpTxEvent which moved an lp transaction into success
+const OutboxTopicLpTxEvent = "lp_tx_event"
+
+// OutboxMessage is a message written in the same transaction as the change it announces,
+// and delivered at least once by the relay
+type OutboxMessage struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null" json:"createdAt"`
+	UpdatedAt time.Time `json:"-"`
+	Topic     string    `gorm:"not null" json:"topic"`
+	// Key groups related messages, like the tx hash of an lp transaction
+	Key string `gorm:"not null;default:''" json:"key"`
+	// Payload is the json encoded message body
+	Payload       string    `gorm:"type:text;not null" json:"payload"`
+	State         string    `gorm:"not null;default:'pending'" json:"-"`
+	Attempts      int       `gorm:"not null;default:0" json:"-"`
+	NextAttemptAt time.Time `gorm:"not null" json:"-"`
+	// LockedUntil is when a message claimed by a relay is considered abandoned and claimed again
+	LockedUntil *time.Time `json:"-"`
+	LastError   string     `gorm:"type:text;not null;default:''" json:"-"`
+	DeliveredAt *time.Time `json:"-"`
+}